@@ -5,9 +5,18 @@ package gorocksdb
 import "C"
 import (
 	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"time"
 	"unsafe"
 )
 
+// the default interval to poll the size of the destination directories of
+// the backups and the restores with a progress callback.
+const backupProgressPollInterval = 100 * time.Millisecond
+
 // BackupEngineInfo represents the information about the backups
 // in a backup engine instance. Use this to get the state of the
 // backup like number of backups and their ids and timestamps etc.
@@ -75,10 +84,14 @@ func (ro *RestoreOptions) Destroy() {
 
 // BackupEngine is a reusable handle to a RocksDB Backup, created by
 // OpenBackupEngine.
+// The C API has no functions to delete a single backup, to attach app
+// metadata to a backup or to list the files of a backup, so they are not
+// provided, PurgeOldBackups deletes the oldest backups.
 type BackupEngine struct {
-	c    *C.rocksdb_backup_engine_t
-	path string
-	opts *Options
+	c     *C.rocksdb_backup_engine_t
+	path  string
+	opts  *Options
+	bopts *BackupableDBOptions
 }
 
 // OpenBackupEngine opens a backup engine with specified options.
@@ -99,6 +112,26 @@ func OpenBackupEngine(opts *Options, path string) (*BackupEngine, error) {
 	}, nil
 }
 
+// OpenBackupEngineWithOptions opens a backup engine with the backup options,
+// which allow to throttle the backups and restores and to get the progress
+// of the backups.
+func OpenBackupEngineWithOptions(opts *BackupableDBOptions) (*BackupEngine, error) {
+	var cErr *C.char
+	cEnv := C.rocksdb_create_default_env()
+	defer C.rocksdb_env_destroy(cEnv)
+
+	be := C.rocksdb_backup_engine_open_opts(opts.c, cEnv, &cErr)
+	if cErr != nil {
+		defer C.free(unsafe.Pointer(cErr))
+		return nil, errors.New(C.GoString(cErr))
+	}
+	return &BackupEngine{
+		c:     be,
+		path:  opts.backupDir,
+		bopts: opts,
+	}, nil
+}
+
 // UnsafeGetBackupEngine returns the underlying c backup engine.
 func (b *BackupEngine) UnsafeGetBackupEngine() unsafe.Pointer {
 	return unsafe.Pointer(b.c)
@@ -106,14 +139,29 @@ func (b *BackupEngine) UnsafeGetBackupEngine() unsafe.Pointer {
 
 // CreateNewBackup takes a new backup from db.
 func (b *BackupEngine) CreateNewBackup(db *DB) error {
-	var cErr *C.char
+	return b.CreateNewBackupFlush(db, false)
+}
 
-	C.rocksdb_backup_engine_create_new_backup(b.c, db.c, &cErr)
+// CreateNewBackupFlush takes a new backup from db. If flushBeforeBackup is
+// true, the memtable is flushed before the backup so the WAL files are not
+// needed to be backed up.
+// The read lock of the db is held during the backup, so closing the db
+// waits for the backup to finish.
+func (b *BackupEngine) CreateNewBackupFlush(db *DB, flushBeforeBackup bool) error {
+	var cErr *C.char
+	db.RLock()
+	if db.opened == 0 {
+		db.RUnlock()
+		return errDBClosed
+	}
+	b.withProgress([]string{b.path}, false, func() {
+		C.rocksdb_backup_engine_create_new_backup_flush(b.c, db.c, boolToChar(flushBeforeBackup), &cErr)
+	})
+	db.RUnlock()
 	if cErr != nil {
 		defer C.free(unsafe.Pointer(cErr))
 		return errors.New(C.GoString(cErr))
 	}
-
 	return nil
 }
 
@@ -125,6 +173,156 @@ func (b *BackupEngine) GetInfo() *BackupEngineInfo {
 	}
 }
 
+// VerifyBackup checks that all the files of the backup with the id exist
+// and have the expected sizes. If verifyWithChecksum is true, the backup is
+// also restored into a temporary directory, which verifies the checksums of
+// all the files, so it reads all the files and needs the space of a restore.
+func (b *BackupEngine) VerifyBackup(backupID int64, verifyWithChecksum bool) error {
+	var cErr *C.char
+	C.rocksdb_backup_engine_verify_backup(b.c, C.uint32_t(backupID), &cErr)
+	if cErr != nil {
+		defer C.free(unsafe.Pointer(cErr))
+		return errors.New(C.GoString(cErr))
+	}
+	if !verifyWithChecksum {
+		return nil
+	}
+	dir, err := ioutil.TempDir("", "gorocksdb-verifybackup")
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(dir)
+	cDir := C.CString(dir)
+	defer C.free(unsafe.Pointer(cDir))
+	ro := NewRestoreOptions()
+	defer ro.Destroy()
+	C.rocksdb_backup_engine_restore_db_from_backup(b.c, cDir, cDir, ro.c, C.uint32_t(backupID), &cErr)
+	if cErr != nil {
+		defer C.free(unsafe.Pointer(cErr))
+		return errors.New(C.GoString(cErr))
+	}
+	return nil
+}
+
+// withProgress runs fn, and meanwhile reports the bytes copied into the
+// dirs to the progress callback of the backup options if it's set. If
+// replace is true, fn replaces the files in the dirs, so all the files in
+// the dirs are counted as copied, otherwise only the added bytes are.
+func (b *BackupEngine) withProgress(dirs []string, replace bool, fn func()) {
+	if b.bopts == nil || b.bopts.progress == nil {
+		fn()
+		return
+	}
+	w := &progressWatcher{
+		fn:       b.bopts.progress,
+		interval: uint64(C.rocksdb_backup_engine_options_get_callback_trigger_interval_size(b.bopts.c)),
+		poll:     b.bopts.pollInterval,
+		dirs:     dirs,
+		stopc:    make(chan struct{}),
+		done:     make(chan struct{}),
+	}
+	if w.poll <= 0 {
+		w.poll = backupProgressPollInterval
+	}
+	if !replace {
+		w.base = w.size()
+	}
+	go w.run()
+	fn()
+	close(w.stopc)
+	<-w.done
+}
+
+// progressWatcher polls the size of the dirs every poll and calls fn with
+// the bytes copied into them.
+type progressWatcher struct {
+	fn       BackupProgressFunc
+	interval uint64
+	poll     time.Duration
+	dirs     []string
+	base     uint64
+	reported uint64
+	stopc    chan struct{}
+	done     chan struct{}
+}
+
+func (w *progressWatcher) run() {
+	defer close(w.done)
+	ticker := time.NewTicker(w.poll)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-w.stopc:
+			if copied := w.copied(); copied > w.reported {
+				w.fn(copied)
+			}
+			return
+		case <-ticker.C:
+			if copied := w.copied(); copied > w.reported && copied-w.reported >= w.interval {
+				w.reported = copied
+				w.fn(copied)
+			}
+		}
+	}
+}
+
+func (w *progressWatcher) size() uint64 {
+	var size uint64
+	for _, dir := range w.dirs {
+		size += dirSize(dir)
+	}
+	return size
+}
+
+func (w *progressWatcher) copied() uint64 {
+	if size := w.size(); size > w.base {
+		return size - w.base
+	}
+	return 0
+}
+
+// restoreDirs returns the destination directories of a restore.
+func restoreDirs(dbDir, walDir string) []string {
+	if walDir == "" || filepath.Clean(walDir) == filepath.Clean(dbDir) {
+		return []string{dbDir}
+	}
+	return []string{dbDir, walDir}
+}
+
+// dirSize returns the total size of the regular files under the dir, the
+// files removed during the walk are skipped.
+func dirSize(dir string) uint64 {
+	var size uint64
+	filepath.Walk(dir, func(path string, fi os.FileInfo, err error) error {
+		if err == nil && fi.Mode().IsRegular() {
+			size += uint64(fi.Size())
+		}
+		return nil
+	})
+	return size
+}
+
+// RestoreDBFromBackup restores the backup with the id to dbDir. walDir
+// is where the write ahead logs are restored to and usually the same as dbDir.
+func (b *BackupEngine) RestoreDBFromBackup(backupID int64, dbDir, walDir string, ro *RestoreOptions) error {
+	var cErr *C.char
+	cDbDir := C.CString(dbDir)
+	cWalDir := C.CString(walDir)
+	defer func() {
+		C.free(unsafe.Pointer(cDbDir))
+		C.free(unsafe.Pointer(cWalDir))
+	}()
+
+	b.withProgress(restoreDirs(dbDir, walDir), true, func() {
+		C.rocksdb_backup_engine_restore_db_from_backup(b.c, cDbDir, cWalDir, ro.c, C.uint32_t(backupID), &cErr)
+	})
+	if cErr != nil {
+		defer C.free(unsafe.Pointer(cErr))
+		return errors.New(C.GoString(cErr))
+	}
+	return nil
+}
+
 // RestoreDBFromLatestBackup restores the latest backup to dbDir. walDir
 // is where the write ahead logs are restored to and usually the same as dbDir.
 func (b *BackupEngine) RestoreDBFromLatestBackup(dbDir, walDir string, ro *RestoreOptions) error {
@@ -136,7 +334,9 @@ func (b *BackupEngine) RestoreDBFromLatestBackup(dbDir, walDir string, ro *Resto
 		C.free(unsafe.Pointer(cWalDir))
 	}()
 
-	C.rocksdb_backup_engine_restore_db_from_latest_backup(b.c, cDbDir, cWalDir, ro.c, &cErr)
+	b.withProgress(restoreDirs(dbDir, walDir), true, func() {
+		C.rocksdb_backup_engine_restore_db_from_latest_backup(b.c, cDbDir, cWalDir, ro.c, &cErr)
+	})
 	if cErr != nil {
 		defer C.free(unsafe.Pointer(cErr))
 		return errors.New(C.GoString(cErr))
@@ -164,19 +364,35 @@ func (b *BackupEngine) PurgeOldBackups(db *DB, num_backups_to_keep int) error {
 type Checkpoint struct {
 	c   *C.rocksdb_checkpoint_t
 	cDb *C.rocksdb_t
+	// db is the db the checkpoint is created from, the checkpoint is
+	// released when the db is closed.
+	db *DB
+	// released is set once the checkpoint is released by closing the db.
+	released int32
 }
 
+// NewCheckpoint creates a checkpoint object of the db, which should be
+// protected by rlock by caller like the iterators and the snapshots.
 func NewCheckpoint(db *DB) (*Checkpoint, error) {
 	var cErr *C.char
+	if db.opened == 0 {
+		return nil, errDBClosed
+	}
 	cCheck := C.rocksdb_checkpoint_object_create(db.c, &cErr)
 	if cErr != nil {
 		defer C.free(unsafe.Pointer(cErr))
 		return nil, errors.New(C.GoString(cErr))
 	}
-	return &Checkpoint{
+	c := &Checkpoint{
 		c:   cCheck,
 		cDb: db.c,
-	}, nil
+		db:  db,
+	}
+	if !db.track(c) {
+		C.rocksdb_checkpoint_object_destroy(cCheck)
+		return nil, errDBClosed
+	}
+	return c, nil
 }
 
 func (c *Checkpoint) Save(dir string, log_size_for_flush uint64) error {
@@ -185,6 +401,9 @@ func (c *Checkpoint) Save(dir string, log_size_for_flush uint64) error {
 		cDir = C.CString(dir)
 	)
 	defer C.free(unsafe.Pointer(cDir))
+	if atomic.LoadInt32(&c.released) != 0 {
+		return errDBClosed
+	}
 	C.rocksdb_checkpoint_create(c.c, cDir,
 		C.uint64_t(log_size_for_flush), &cErr)
 	if cErr != nil {
@@ -194,7 +413,13 @@ func (c *Checkpoint) Save(dir string, log_size_for_flush uint64) error {
 	return nil
 }
 
-func (c *Checkpoint) Destroy() {
+func (c *Checkpoint) release() {
+	atomic.StoreInt32(&c.released, 1)
 	C.rocksdb_checkpoint_object_destroy(c.c)
-	c.c, c.cDb = nil, nil
+}
+
+// Destroy destroys the checkpoint object, it does nothing if the checkpoint
+// is released by closing the db.
+func (c *Checkpoint) Destroy() {
+	c.db.untrack(c)
 }
@@ -0,0 +1,168 @@
+package gorocksdb
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"time"
+)
+
+var (
+	errNoRestorePoint      = errors.New("target sequence or target time should be set")
+	errNoWalDir            = errors.New("wal dir should be set")
+	errBackupAfterTarget   = errors.New("the backup is taken after the target time")
+	errWALNotReachedTarget = errors.New("the wal files end before the target sequence")
+)
+
+// PointInTimeRestoreOptions represents the options used by
+// BackupEngine.RestoreDBToPointInTime.
+type PointInTimeRestoreOptions struct {
+	// Options is used to open the restored db to replay the logs.
+	Options *Options
+	// ColumnFamilyNames and ColumnFamilyOptions are the column families of
+	// the restored db. If not set, only the default column family is opened,
+	// and the logs written to the other column families can not be replayed.
+	ColumnFamilyNames   []string
+	ColumnFamilyOptions []*Options
+	// RestoreOptions is used to restore the backup, the default options
+	// are used if nil.
+	RestoreOptions *RestoreOptions
+	// WalDir is the wal dir of the backed up db, which is the db dir if
+	// the wal dir is not set. The live logs in it and the archived logs in
+	// its archive sub dir are replayed, so the db should keep the archived
+	// logs by SetWALTtlSeconds or SetWalSizeLimitMb.
+	WalDir string
+	// TargetSequence is the last sequence number to be restored, a write
+	// batch is replayed only if all of its sequence numbers are not after it.
+	TargetSequence uint64
+	// TargetTime is the wall-clock time to be restored. The logs have no
+	// timestamps, so a log file is replayed only if it is modified before
+	// the target time, which means the writes to the log file being written
+	// at the target time are not restored.
+	TargetTime time.Time
+}
+
+// RestoreDBToPointInTime restores the backup with the id to dbDir, and
+// replays the archived logs of the backed up db up to the target sequence
+// or the target time, whichever comes first. It returns the latest sequence
+// number of the restored db.
+// The logs are replayed with the default write policy, a missing log between
+// the backup and the target makes the restore fail rather than leaving a db
+// with a hole in its history.
+func (b *BackupEngine) RestoreDBToPointInTime(backupID int64, dbDir string, opts *PointInTimeRestoreOptions) (uint64, error) {
+	if opts.TargetSequence == 0 && opts.TargetTime.IsZero() {
+		return 0, errNoRestorePoint
+	}
+	if opts.WalDir == "" {
+		return 0, errNoWalDir
+	}
+	if !opts.TargetTime.IsZero() {
+		ts, err := b.backupTimestamp(backupID)
+		if err != nil {
+			return 0, err
+		}
+		if ts > opts.TargetTime.Unix() {
+			return 0, errBackupAfterTarget
+		}
+	}
+	// list the logs before restoring, so the logs archived during the
+	// restore are not mixed in.
+	files, err := listWALFiles(opts.WalDir)
+	if err != nil {
+		return 0, err
+	}
+
+	ro := opts.RestoreOptions
+	if ro == nil {
+		ro = NewRestoreOptions()
+		defer ro.Destroy()
+	}
+	if err := b.RestoreDBFromBackup(backupID, dbDir, dbDir, ro); err != nil {
+		return 0, err
+	}
+
+	var db *DB
+	if len(opts.ColumnFamilyNames) > 0 {
+		var cfs []*ColumnFamilyHandle
+		db, cfs, err = OpenDbColumnFamilies(opts.Options, dbDir, opts.ColumnFamilyNames, opts.ColumnFamilyOptions)
+		if err != nil {
+			return 0, err
+		}
+		defer func() {
+			for _, cf := range cfs {
+				cf.Destroy()
+			}
+		}()
+	} else {
+		db, err = OpenDb(opts.Options, dbDir)
+		if err != nil {
+			return 0, err
+		}
+	}
+	defer db.Close()
+	return replayWAL(db, files, opts.TargetSequence, opts.TargetTime)
+}
+
+func (b *BackupEngine) backupTimestamp(backupID int64) (int64, error) {
+	info := b.GetInfo()
+	defer info.Destroy()
+	for i := 0; i < info.GetCount(); i++ {
+		if info.GetBackupId(i) == backupID {
+			return info.GetTimestamp(i), nil
+		}
+	}
+	return 0, fmt.Errorf("backup %d not found", backupID)
+}
+
+// replayWAL writes the batches in the log files which follow the latest
+// sequence number of the db to the db, until the target is reached.
+func replayWAL(db *DB, files []walFile, targetSeq uint64, targetTime time.Time) (uint64, error) {
+	wo := NewDefaultWriteOptions()
+	defer wo.Destroy()
+
+	last := db.GetLatestSequenceNumber()
+	for _, f := range files {
+		if !targetTime.IsZero() && f.modTime.After(targetTime) {
+			return last, nil
+		}
+		r, err := newWALReader(f)
+		if err != nil {
+			return last, err
+		}
+		for {
+			data, err := r.Next()
+			if err == io.EOF {
+				break
+			}
+			if err != nil {
+				return last, fmt.Errorf("read wal %v: %v", f.path, err)
+			}
+			if len(data) < kHeader {
+				return last, fmt.Errorf("read wal %v: %v", f.path, errWALCorrupted)
+			}
+			seq := binary.LittleEndian.Uint64(data[0:8])
+			count := uint64(binary.LittleEndian.Uint32(data[8:12]))
+			if count == 0 || seq+count-1 <= last {
+				continue
+			}
+			if seq != last+1 {
+				return last, fmt.Errorf("wal from sequence %v to %v is missing", last+1, seq-1)
+			}
+			if targetSeq > 0 && seq+count-1 > targetSeq {
+				return last, nil
+			}
+			wb := WriteBatchFrom(data)
+			err = db.Write(wo, wb)
+			wb.Destroy()
+			if err != nil {
+				return last, err
+			}
+			last = seq + count - 1
+		}
+	}
+	if targetSeq > last {
+		return last, errWALNotReachedTarget
+	}
+	return last, nil
+}
@@ -0,0 +1,103 @@
+package gorocksdb
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/facebookgo/ensure"
+)
+
+func TestBackupEngineRestoreDBToPointInTime(t *testing.T) {
+	db := newTestDB(t, "TestBackupEngineRestoreDBToPointInTime", func(opts *Options) {
+		opts.SetWALTtlSeconds(3600)
+	})
+	defer db.Close()
+
+	var (
+		wo = NewDefaultWriteOptions()
+		ro = NewDefaultReadOptions()
+	)
+
+	dir, err := ioutil.TempDir("", "gorocksdb-TestBackupEngineRestoreDBToPointInTime")
+	ensure.Nil(t, err)
+	opts := NewDefaultOptions()
+	be, err := OpenBackupEngine(opts, dir)
+	ensure.Nil(t, err)
+	defer be.Close()
+
+	// sequence 1 is in the backup, 2 and 3 are only in the logs.
+	ensure.Nil(t, db.Put(wo, []byte("key1"), []byte("val1")))
+	ensure.Nil(t, be.CreateNewBackup(db))
+	ensure.Nil(t, db.Put(wo, []byte("key2"), []byte("val2")))
+	ensure.Nil(t, db.Put(wo, []byte("key3"), []byte("val3")))
+
+	info := be.GetInfo()
+	backupID := info.GetBackupId(0)
+	info.Destroy()
+
+	restoreDir, err := ioutil.TempDir("", "gorocksdb-TestBackupEngineRestoreDBToPointInTime-restore")
+	ensure.Nil(t, err)
+	seq, err := be.RestoreDBToPointInTime(backupID, restoreDir, &PointInTimeRestoreOptions{
+		Options:        opts,
+		WalDir:         db.Name(),
+		TargetSequence: 2,
+	})
+	ensure.Nil(t, err)
+	ensure.DeepEqual(t, seq, uint64(2))
+
+	restoredDB, err := OpenDb(opts, restoreDir)
+	ensure.Nil(t, err)
+	defer restoredDB.Close()
+	v, err := restoredDB.GetBytes(ro, []byte("key2"))
+	ensure.Nil(t, err)
+	ensure.DeepEqual(t, v, []byte("val2"))
+	v, err = restoredDB.GetBytes(ro, []byte("key3"))
+	ensure.Nil(t, err)
+	ensure.True(t, v == nil)
+
+	// the logs can not reach a sequence which is not written yet.
+	restoreDir2, err := ioutil.TempDir("", "gorocksdb-TestBackupEngineRestoreDBToPointInTime-restore2")
+	ensure.Nil(t, err)
+	_, err = be.RestoreDBToPointInTime(backupID, restoreDir2, &PointInTimeRestoreOptions{
+		Options:        opts,
+		WalDir:         db.Name(),
+		TargetSequence: 10,
+	})
+	ensure.DeepEqual(t, err, errWALNotReachedTarget)
+
+	// the wal dir is required.
+	_, err = be.RestoreDBToPointInTime(backupID, restoreDir2, &PointInTimeRestoreOptions{
+		Options:        opts,
+		TargetSequence: 2,
+	})
+	ensure.DeepEqual(t, err, errNoWalDir)
+}
+
+func TestWALReaderArchivedAfterListed(t *testing.T) {
+	db := newTestDB(t, "TestWALReaderArchivedAfterListed", func(opts *Options) {
+		opts.SetWALTtlSeconds(3600)
+	})
+	defer db.Close()
+
+	wo := NewDefaultWriteOptions()
+	ensure.Nil(t, db.Put(wo, []byte("key1"), []byte("val1")))
+	files, err := listWALFiles(db.Name())
+	ensure.Nil(t, err)
+	ensure.DeepEqual(t, len(files), 1)
+	ensure.DeepEqual(t, filepath.Dir(files[0].path), db.Name())
+
+	// the flush switches to a new log and archives the listed one.
+	fo := NewDefaultFlushOptions()
+	defer fo.Destroy()
+	ensure.Nil(t, db.Flush(fo))
+	_, err = os.Stat(files[0].path)
+	ensure.True(t, os.IsNotExist(err))
+
+	r, err := newWALReader(files[0])
+	ensure.Nil(t, err)
+	data, err := r.Next()
+	ensure.Nil(t, err)
+	ensure.True(t, len(data) > kHeader)
+}
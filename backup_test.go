@@ -0,0 +1,145 @@
+package gorocksdb
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/facebookgo/ensure"
+)
+
+func TestBackupEngine(t *testing.T) {
+	db := newTestDB(t, "TestBackupEngine", nil)
+	defer db.Close()
+
+	var (
+		givenKey  = []byte("hello")
+		givenVal1 = []byte("world1")
+		givenVal2 = []byte("world2")
+		wo        = NewDefaultWriteOptions()
+		ro        = NewDefaultReadOptions()
+	)
+
+	dir, err := ioutil.TempDir("", "gorocksdb-TestBackupEngine")
+	ensure.Nil(t, err)
+	opts := NewDefaultOptions()
+	be, err := OpenBackupEngine(opts, dir)
+	ensure.Nil(t, err)
+	defer be.Close()
+
+	ensure.Nil(t, db.Put(wo, givenKey, givenVal1))
+	ensure.Nil(t, be.CreateNewBackupFlush(db, true))
+	ensure.Nil(t, db.Put(wo, givenKey, givenVal2))
+	ensure.Nil(t, be.CreateNewBackup(db))
+
+	info := be.GetInfo()
+	ensure.DeepEqual(t, info.GetCount(), 2)
+	firstID := info.GetBackupId(0)
+	secondID := info.GetBackupId(1)
+	ensure.True(t, info.GetNumFiles(0) > 0)
+	info.Destroy()
+
+	ensure.Nil(t, be.VerifyBackup(firstID, true))
+	ensure.Nil(t, be.VerifyBackup(secondID, false))
+
+	// restore the first backup
+	restoreDir, err := ioutil.TempDir("", "gorocksdb-TestBackupEngine-restore")
+	ensure.Nil(t, err)
+	restoreOpts := NewRestoreOptions()
+	defer restoreOpts.Destroy()
+	ensure.Nil(t, be.RestoreDBFromBackup(firstID, restoreDir, restoreDir, restoreOpts))
+	restoredDB, err := OpenDb(opts, restoreDir)
+	ensure.Nil(t, err)
+	v, err := restoredDB.GetBytes(ro, givenKey)
+	ensure.Nil(t, err)
+	ensure.DeepEqual(t, v, givenVal1)
+	restoredDB.Close()
+
+	// purge the first backup
+	ensure.Nil(t, be.PurgeOldBackups(db, 1))
+	ensure.NotNil(t, be.VerifyBackup(firstID, false))
+	info = be.GetInfo()
+	ensure.DeepEqual(t, info.GetCount(), 1)
+	info.Destroy()
+
+	db.Close()
+	ensure.DeepEqual(t, be.CreateNewBackup(db), errDBClosed)
+}
+
+func TestBackupEngineVerifyChecksum(t *testing.T) {
+	db := newTestDB(t, "TestBackupEngineVerifyChecksum", nil)
+	defer db.Close()
+
+	wo := NewDefaultWriteOptions()
+	ensure.Nil(t, db.Put(wo, []byte("hello"), []byte("world")))
+
+	dir, err := ioutil.TempDir("", "gorocksdb-TestBackupEngineVerifyChecksum")
+	ensure.Nil(t, err)
+	be, err := OpenBackupEngine(NewDefaultOptions(), dir)
+	ensure.Nil(t, err)
+	defer be.Close()
+	ensure.Nil(t, be.CreateNewBackupFlush(db, true))
+
+	info := be.GetInfo()
+	id := info.GetBackupId(0)
+	info.Destroy()
+
+	// corrupt a table file without changing its size
+	files, err := filepath.Glob(filepath.Join(dir, "shared*", "*.sst"))
+	ensure.Nil(t, err)
+	ensure.True(t, len(files) > 0)
+	path := files[0]
+	data, err := ioutil.ReadFile(path)
+	ensure.Nil(t, err)
+	data[0] ^= 0xff
+	ensure.Nil(t, ioutil.WriteFile(path, data, 0644))
+	ensure.Nil(t, be.VerifyBackup(id, false))
+	ensure.NotNil(t, be.VerifyBackup(id, true))
+}
+
+func TestBackupEngineWithOptions(t *testing.T) {
+	db := newTestDB(t, "TestBackupEngineWithOptions", nil)
+	defer db.Close()
+
+	wo := NewDefaultWriteOptions()
+	for i := 0; i < 100; i++ {
+		ensure.Nil(t, db.Put(wo, []byte{byte(i)}, make([]byte, 1024)))
+	}
+
+	dir, err := ioutil.TempDir("", "gorocksdb-TestBackupEngineWithOptions")
+	ensure.Nil(t, err)
+	var copied uint64
+	bopts := NewBackupableDBOptions(dir)
+	defer bopts.Destroy()
+	bopts.SetShareTableFiles(true)
+	bopts.SetBackupRateLimit(10 << 20)
+	bopts.SetRestoreRateLimit(10 << 20)
+	bopts.SetMaxBackgroundOperations(2)
+	bopts.SetCallbackTriggerIntervalSize(1024)
+	bopts.SetProgressPollInterval(10 * time.Millisecond)
+	bopts.SetProgressCallback(func(n uint64) {
+		ensure.True(t, n > atomic.LoadUint64(&copied))
+		atomic.StoreUint64(&copied, n)
+	})
+	be, err := OpenBackupEngineWithOptions(bopts)
+	ensure.Nil(t, err)
+	defer be.Close()
+
+	ensure.Nil(t, be.CreateNewBackup(db))
+	info := be.GetInfo()
+	ensure.DeepEqual(t, info.GetCount(), 1)
+	// the meta file of the backup is counted too
+	ensure.True(t, atomic.LoadUint64(&copied) >= uint64(info.GetSize(0)))
+	info.Destroy()
+
+	// the restore reports its progress too
+	atomic.StoreUint64(&copied, 0)
+	restoreDir, err := ioutil.TempDir("", "gorocksdb-TestBackupEngineWithOptions-restore")
+	ensure.Nil(t, err)
+	restoreOpts := NewRestoreOptions()
+	defer restoreOpts.Destroy()
+	ensure.Nil(t, be.RestoreDBFromLatestBackup(restoreDir, restoreDir, restoreOpts))
+	ensure.True(t, atomic.LoadUint64(&copied) > 0)
+}
@@ -0,0 +1,390 @@
+// Package bulkload loads large amounts of key values into a gorocksdb
+// database by writing sst files offline and ingesting them in one call,
+// which is much faster than writing through DB.Put or WriteBatch.
+//
+// The input can be one sorted stream, loaded by LoadSorted, or several
+// unsorted shards, loaded by Load, which are sorted and spilled to the
+// temporary directory before the sst files are written.
+//
+//	opts := bulkload.NewDefaultOptions(dbOpts)
+//	opts.TempDir = "/path/to/tmp"
+//	res, err := bulkload.Load(db, []bulkload.Source{shard1, shard2}, opts)
+package bulkload
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"runtime"
+	"sync"
+
+	"github.com/youzan/gorocksdb"
+)
+
+var (
+	errNilDBOptions = errors.New("bulkload: db options must be provided")
+	errNotAscending = errors.New("bulkload: keys are not in strictly ascending order")
+)
+
+// Source is a stream of key values to load.
+type Source interface {
+	// Next returns the next key value pair, or io.EOF if the stream is
+	// drained. The returned slices are copied and can be reused by the
+	// source after the next call.
+	Next() (key []byte, value []byte, err error)
+}
+
+// Stage is the stage of a loading.
+type Stage int
+
+// Stages of a loading.
+const (
+	StageSorting Stage = iota
+	StageWriting
+	StageIngesting
+	StageDone
+)
+
+func (s Stage) String() string {
+	switch s {
+	case StageSorting:
+		return "sorting"
+	case StageWriting:
+		return "writing"
+	case StageIngesting:
+		return "ingesting"
+	case StageDone:
+		return "done"
+	}
+	return fmt.Sprintf("stage(%d)", int(s))
+}
+
+// Progress reports how much of the input has been handled in the stage.
+type Progress struct {
+	Stage Stage
+	// Entries and Bytes are the number and the raw size of the key values
+	// sorted or written so far.
+	Entries uint64
+	Bytes   uint64
+	// Files is the number of the spilled files in StageSorting and the sst
+	// files in the later stages.
+	Files int
+}
+
+// Result is the summary of a finished loading.
+type Result struct {
+	Entries uint64
+	Bytes   uint64
+	Files   int
+	// ApproximateSize is the size on disk of the loaded key range after the
+	// ingestion, as returned by GetApproximateSizes.
+	ApproximateSize uint64
+}
+
+// Options controls a loading.
+type Options struct {
+	// DBOptions is used to write the sst files, it should be the options of
+	// the database or the column family the files are ingested into.
+	DBOptions *gorocksdb.Options
+	// IngestOptions is used to ingest the sst files. If nil, the default
+	// options with move files enabled are used.
+	IngestOptions *gorocksdb.IngestExternalFileOptions
+	// CF is the column family to load into, or the default column family
+	// if nil.
+	CF *gorocksdb.ColumnFamilyHandle
+	// TempDir is the directory for the spilled and sst files, a sub
+	// directory is created in it and removed after the loading.
+	// The system temporary directory is used if empty.
+	TempDir string
+	// TargetFileSize is the raw size of the key values in each sst file.
+	TargetFileSize uint64
+	// SortBufferSize is the raw size of the key values each shard buffers
+	// in memory before the sorted run is spilled to disk.
+	SortBufferSize uint64
+	// Parallelism is the number of the sst files written concurrently.
+	Parallelism int
+	// Compare is the order of the keys, it must be the same as the
+	// comparator of the database. bytes.Compare is used if nil.
+	Compare func(a, b []byte) int
+	// Progress is called after each spilled or sst file is finished and
+	// when a stage changes. It is never called concurrently.
+	Progress func(Progress)
+}
+
+// NewDefaultOptions creates the default Options for the database options.
+func NewDefaultOptions(dbOpts *gorocksdb.Options) *Options {
+	return &Options{
+		DBOptions:      dbOpts,
+		TargetFileSize: 64 << 20,
+		SortBufferSize: 64 << 20,
+		Parallelism:    runtime.NumCPU(),
+	}
+}
+
+// LoadSorted loads the key values of the source, which must be in strictly
+// ascending order, into the database.
+func LoadSorted(db *gorocksdb.DB, src Source, opts *Options) (*Result, error) {
+	l, err := newLoader(db, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer l.cleanup()
+	return l.load(src)
+}
+
+// Load sorts the key values of the shards and loads them into the database.
+// If a key is duplicated, the value from the shard of the larger index wins,
+// and the later value wins in the same shard.
+func Load(db *gorocksdb.DB, shards []Source, opts *Options) (*Result, error) {
+	l, err := newLoader(db, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer l.cleanup()
+	runs, err := l.sortShards(shards)
+	if err != nil {
+		return nil, err
+	}
+	src, err := newMergeSource(runs, l.compare)
+	if err != nil {
+		return nil, err
+	}
+	defer src.Close()
+	return l.load(src)
+}
+
+type loader struct {
+	db      *gorocksdb.DB
+	opts    Options
+	compare func(a, b []byte) int
+	dir     string
+
+	progressMu sync.Mutex
+	progress   Progress
+}
+
+func newLoader(db *gorocksdb.DB, opts *Options) (*loader, error) {
+	if opts == nil || opts.DBOptions == nil {
+		return nil, errNilDBOptions
+	}
+	l := &loader{db: db, opts: *opts, compare: opts.Compare}
+	if l.compare == nil {
+		l.compare = bytes.Compare
+	}
+	if l.opts.TargetFileSize == 0 {
+		l.opts.TargetFileSize = 64 << 20
+	}
+	if l.opts.SortBufferSize == 0 {
+		l.opts.SortBufferSize = 64 << 20
+	}
+	if l.opts.Parallelism <= 0 {
+		l.opts.Parallelism = runtime.NumCPU()
+	}
+	dir, err := ioutil.TempDir(l.opts.TempDir, "gorocksdb-bulkload")
+	if err != nil {
+		return nil, err
+	}
+	l.dir = dir
+	return l, nil
+}
+
+func (l *loader) cleanup() {
+	os.RemoveAll(l.dir)
+}
+
+// report updates the progress and calls the callback.
+func (l *loader) report(f func(p *Progress)) {
+	l.progressMu.Lock()
+	defer l.progressMu.Unlock()
+	f(&l.progress)
+	if l.opts.Progress != nil {
+		l.opts.Progress(l.progress)
+	}
+}
+
+func (l *loader) load(src Source) (*Result, error) {
+	l.report(func(p *Progress) {
+		*p = Progress{Stage: StageWriting}
+	})
+	files, first, last, err := l.writeFiles(src)
+	if err != nil {
+		return nil, err
+	}
+	res := &Result{
+		Entries: l.progress.Entries,
+		Bytes:   l.progress.Bytes,
+		Files:   len(files),
+	}
+	if len(files) == 0 {
+		l.report(func(p *Progress) { p.Stage = StageDone })
+		return res, nil
+	}
+
+	l.report(func(p *Progress) { p.Stage = StageIngesting })
+	ingestOpts := l.opts.IngestOptions
+	if ingestOpts == nil {
+		ingestOpts = gorocksdb.NewDefaultIngestExternalFileOptions()
+		ingestOpts.SetMoveFiles(true)
+		defer ingestOpts.Destroy()
+	}
+	if l.opts.CF != nil {
+		err = l.db.IngestExternalFileCF(l.opts.CF, files, ingestOpts)
+	} else {
+		err = l.db.IngestExternalFile(files, ingestOpts)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	r := []gorocksdb.Range{{Start: first, Limit: l.rangeLimit(last)}}
+	var sizes []uint64
+	if l.opts.CF != nil {
+		sizes = l.db.GetApproximateSizesCF(l.opts.CF, r, true)
+	} else {
+		sizes = l.db.GetApproximateSizes(r, true)
+	}
+	if len(sizes) > 0 {
+		res.ApproximateSize = sizes[0]
+	}
+	l.report(func(p *Progress) { p.Stage = StageDone })
+	return res, nil
+}
+
+// rangeLimit returns the exclusive limit of the range ending at last. The
+// key after last in bytewise order is used if the comparator orders it
+// after last, otherwise the range ends before last and the size of the
+// last key is not counted.
+func (l *loader) rangeLimit(last []byte) []byte {
+	limit := append(append([]byte(nil), last...), 0)
+	if l.compare(last, limit) < 0 {
+		return limit
+	}
+	return last
+}
+
+// chunk is the key values of a sst file.
+type chunk struct {
+	index  int
+	keys   [][]byte
+	values [][]byte
+	size   uint64
+}
+
+// writeFiles splits the sorted source into chunks of the target file size
+// and writes them to sst files concurrently. It returns the sst files in
+// the key order and the first and last key.
+func (l *loader) writeFiles(src Source) ([]string, []byte, []byte, error) {
+	var (
+		wg       sync.WaitGroup
+		errOnce  sync.Once
+		firstErr error
+		done     = make(chan struct{})
+		chunks   = make(chan *chunk)
+		filesMu  sync.Mutex
+		files    []string
+	)
+	setErr := func(err error) {
+		errOnce.Do(func() {
+			firstErr = err
+			close(done)
+		})
+	}
+	for i := 0; i < l.opts.Parallelism; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			envOpts := gorocksdb.NewDefaultEnvOptions()
+			defer envOpts.Destroy()
+			w := gorocksdb.NewSstFileWriter(envOpts, l.opts.DBOptions)
+			defer w.Destroy()
+			for c := range chunks {
+				path := filepath.Join(l.dir, fmt.Sprintf("%08d.sst", c.index))
+				if err := l.writeFile(w, path, c); err != nil {
+					setErr(err)
+					continue
+				}
+				filesMu.Lock()
+				if len(files) <= c.index {
+					files = append(files, make([]string, c.index+1-len(files))...)
+				}
+				files[c.index] = path
+				filesMu.Unlock()
+				l.report(func(p *Progress) {
+					p.Entries += uint64(len(c.keys))
+					p.Bytes += c.size
+					p.Files++
+				})
+			}
+		}()
+	}
+
+	var (
+		first, last []byte
+		hasLast     bool
+	)
+	err := func() error {
+		defer close(chunks)
+		c := &chunk{}
+		for {
+			key, value, err := src.Next()
+			if err == io.EOF {
+				break
+			}
+			if err != nil {
+				return err
+			}
+			if hasLast && l.compare(last, key) >= 0 {
+				return errNotAscending
+			}
+			key = append([]byte{}, key...)
+			if !hasLast {
+				first = key
+			}
+			last, hasLast = key, true
+			c.keys = append(c.keys, key)
+			c.values = append(c.values, append([]byte(nil), value...))
+			c.size += uint64(len(key) + len(value))
+			if c.size >= l.opts.TargetFileSize {
+				select {
+				case chunks <- c:
+				case <-done:
+					return nil
+				}
+				c = &chunk{index: c.index + 1}
+			}
+		}
+		if len(c.keys) > 0 {
+			select {
+			case chunks <- c:
+			case <-done:
+			}
+		}
+		return nil
+	}()
+	if err != nil {
+		setErr(err)
+	}
+	wg.Wait()
+	if firstErr != nil {
+		return nil, nil, nil, firstErr
+	}
+	return files, first, last, nil
+}
+
+func (l *loader) writeFile(w *gorocksdb.SstFileWriter, path string, c *chunk) error {
+	if err := w.Open(path); err != nil {
+		return err
+	}
+	for i, k := range c.keys {
+		if err := w.Put(k, c.values[i]); err != nil {
+			// finish to close the file, the error is returned by put.
+			w.Finish()
+			return err
+		}
+	}
+	return w.Finish()
+}
@@ -0,0 +1,127 @@
+package bulkload
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"testing"
+
+	"github.com/facebookgo/ensure"
+	"github.com/youzan/gorocksdb"
+)
+
+type sliceSource struct {
+	kvs [][2]string
+	pos int
+}
+
+func (s *sliceSource) Next() ([]byte, []byte, error) {
+	if s.pos >= len(s.kvs) {
+		return nil, nil, io.EOF
+	}
+	kv := s.kvs[s.pos]
+	s.pos++
+	return []byte(kv[0]), []byte(kv[1]), nil
+}
+
+func TestLoadSorted(t *testing.T) {
+	db, dbOpts := newTestDB(t, "TestLoadSorted")
+	defer db.Close()
+
+	src := &sliceSource{}
+	for i := 0; i < 1000; i++ {
+		src.kvs = append(src.kvs, [2]string{fmt.Sprintf("key%04d", i), fmt.Sprintf("val%d", i)})
+	}
+	var stages []Stage
+	opts := NewDefaultOptions(dbOpts)
+	opts.TargetFileSize = 1024
+	opts.Parallelism = 4
+	opts.Progress = func(p Progress) {
+		if len(stages) == 0 || stages[len(stages)-1] != p.Stage {
+			stages = append(stages, p.Stage)
+		}
+	}
+	res, err := LoadSorted(db, src, opts)
+	ensure.Nil(t, err)
+	ensure.DeepEqual(t, res.Entries, uint64(1000))
+	ensure.True(t, res.Files > 1)
+	ensure.DeepEqual(t, stages, []Stage{StageWriting, StageIngesting, StageDone})
+
+	ro := gorocksdb.NewDefaultReadOptions()
+	for _, kv := range src.kvs {
+		v, err := db.GetBytes(ro, []byte(kv[0]))
+		ensure.Nil(t, err)
+		ensure.DeepEqual(t, string(v), kv[1])
+	}
+}
+
+func TestLoadSortedNotAscending(t *testing.T) {
+	db, dbOpts := newTestDB(t, "TestLoadSortedNotAscending")
+	defer db.Close()
+
+	tmpDir, err := ioutil.TempDir("", "gorocksdb-bulkload-tmp")
+	ensure.Nil(t, err)
+	defer os.RemoveAll(tmpDir)
+
+	src := &sliceSource{kvs: [][2]string{{"b", "1"}, {"a", "2"}}}
+	opts := NewDefaultOptions(dbOpts)
+	opts.TempDir = tmpDir
+	_, err = LoadSorted(db, src, opts)
+	ensure.DeepEqual(t, err, errNotAscending)
+
+	// the temporary files are cleaned up
+	files, err := ioutil.ReadDir(tmpDir)
+	ensure.Nil(t, err)
+	ensure.DeepEqual(t, len(files), 0)
+
+	// an empty key is checked too
+	src = &sliceSource{kvs: [][2]string{{"", "1"}, {"", "2"}}}
+	_, err = LoadSorted(db, src, opts)
+	ensure.DeepEqual(t, err, errNotAscending)
+}
+
+func TestRangeLimit(t *testing.T) {
+	l := &loader{compare: bytes.Compare}
+	ensure.DeepEqual(t, l.rangeLimit([]byte("a")), []byte("a\x00"))
+
+	// the key after last in bytewise order is before last in reverse order
+	l.compare = func(a, b []byte) int { return bytes.Compare(b, a) }
+	ensure.DeepEqual(t, l.rangeLimit([]byte("a")), []byte("a"))
+}
+
+func TestLoadShards(t *testing.T) {
+	db, dbOpts := newTestDB(t, "TestLoadShards")
+	defer db.Close()
+
+	shards := []Source{
+		&sliceSource{kvs: [][2]string{{"c", "1"}, {"a", "1"}, {"e", "1"}, {"a", "2"}}},
+		&sliceSource{kvs: [][2]string{{"d", "3"}, {"b", "3"}, {"c", "3"}}},
+	}
+	opts := NewDefaultOptions(dbOpts)
+	opts.SortBufferSize = 4
+	opts.TargetFileSize = 4
+	res, err := Load(db, shards, opts)
+	ensure.Nil(t, err)
+	ensure.DeepEqual(t, res.Entries, uint64(5))
+
+	expected := map[string]string{"a": "2", "b": "3", "c": "3", "d": "3", "e": "1"}
+	ro := gorocksdb.NewDefaultReadOptions()
+	for k, val := range expected {
+		v, err := db.GetBytes(ro, []byte(k))
+		ensure.Nil(t, err)
+		ensure.DeepEqual(t, string(v), val)
+	}
+}
+
+func newTestDB(t *testing.T, name string) (*gorocksdb.DB, *gorocksdb.Options) {
+	dir, err := ioutil.TempDir("", "gorocksdb-"+name)
+	ensure.Nil(t, err)
+
+	opts := gorocksdb.NewDefaultOptions()
+	opts.SetCreateIfMissing(true)
+	db, err := gorocksdb.OpenDb(opts, dir)
+	ensure.Nil(t, err)
+	return db, opts
+}
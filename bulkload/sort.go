@@ -0,0 +1,288 @@
+package bulkload
+
+import (
+	"bufio"
+	"container/heap"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+)
+
+// sortShards reads the shards concurrently, sorts the key values in memory
+// and spills the sorted runs to disk. The runs are returned in the order of
+// the shards, and in the order they are spilled in the same shard.
+func (l *loader) sortShards(shards []Source) ([]string, error) {
+	l.report(func(p *Progress) {
+		*p = Progress{Stage: StageSorting}
+	})
+	var (
+		wg      sync.WaitGroup
+		errOnce sync.Once
+		sortErr error
+		runs    = make([][]string, len(shards))
+	)
+	for i, src := range shards {
+		wg.Add(1)
+		go func(shard int, src Source) {
+			defer wg.Done()
+			files, err := l.sortShard(shard, src)
+			if err != nil {
+				errOnce.Do(func() { sortErr = err })
+				return
+			}
+			runs[shard] = files
+		}(i, src)
+	}
+	wg.Wait()
+	if sortErr != nil {
+		return nil, sortErr
+	}
+	var all []string
+	for _, files := range runs {
+		all = append(all, files...)
+	}
+	return all, nil
+}
+
+func (l *loader) sortShard(shard int, src Source) ([]string, error) {
+	var (
+		files []string
+		buf   = &run{compare: l.compare}
+	)
+	spill := func() error {
+		if len(buf.keys) == 0 {
+			return nil
+		}
+		sort.Stable(buf)
+		path := filepath.Join(l.dir, fmt.Sprintf("shard%04d-run%06d.spill", shard, len(files)))
+		if err := buf.writeTo(path); err != nil {
+			return err
+		}
+		files = append(files, path)
+		l.report(func(p *Progress) {
+			p.Entries += uint64(len(buf.keys))
+			p.Bytes += buf.size
+			p.Files++
+		})
+		buf.reset()
+		return nil
+	}
+	for {
+		key, value, err := src.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		buf.keys = append(buf.keys, append([]byte(nil), key...))
+		buf.values = append(buf.values, append([]byte(nil), value...))
+		buf.size += uint64(len(key) + len(value))
+		if buf.size >= l.opts.SortBufferSize {
+			if err := spill(); err != nil {
+				return nil, err
+			}
+		}
+	}
+	if err := spill(); err != nil {
+		return nil, err
+	}
+	return files, nil
+}
+
+// run is the in memory buffer of a sorted run.
+type run struct {
+	keys    [][]byte
+	values  [][]byte
+	size    uint64
+	compare func(a, b []byte) int
+}
+
+func (r *run) Len() int           { return len(r.keys) }
+func (r *run) Less(i, j int) bool { return r.compare(r.keys[i], r.keys[j]) < 0 }
+func (r *run) Swap(i, j int) {
+	r.keys[i], r.keys[j] = r.keys[j], r.keys[i]
+	r.values[i], r.values[j] = r.values[j], r.values[i]
+}
+
+func (r *run) reset() {
+	r.keys, r.values, r.size = nil, nil, 0
+}
+
+// writeTo writes the run as a sequence of the varint length prefixed key
+// and value.
+func (r *run) writeTo(path string) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	w := bufio.NewWriter(f)
+	var lenBuf [binary.MaxVarintLen64]byte
+	for i, k := range r.keys {
+		for _, b := range [][]byte{k, r.values[i]} {
+			n := binary.PutUvarint(lenBuf[:], uint64(len(b)))
+			if _, err := w.Write(lenBuf[:n]); err != nil {
+				f.Close()
+				return err
+			}
+			if _, err := w.Write(b); err != nil {
+				f.Close()
+				return err
+			}
+		}
+	}
+	if err := w.Flush(); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// runReader reads a spilled run.
+type runReader struct {
+	f     *os.File
+	r     *bufio.Reader
+	order int
+	key   []byte
+	value []byte
+}
+
+func openRun(path string, order int) (*runReader, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	return &runReader{f: f, r: bufio.NewReader(f), order: order}, nil
+}
+
+// next reads the next key value of the run, io.EOF is returned at the end.
+func (rr *runReader) next() error {
+	key, err := rr.readBytes()
+	if err != nil {
+		return err
+	}
+	value, err := rr.readBytes()
+	if err == io.EOF {
+		return io.ErrUnexpectedEOF
+	}
+	if err != nil {
+		return err
+	}
+	rr.key, rr.value = key, value
+	return nil
+}
+
+func (rr *runReader) readBytes() ([]byte, error) {
+	n, err := binary.ReadUvarint(rr.r)
+	if err != nil {
+		return nil, err
+	}
+	b := make([]byte, n)
+	if _, err := io.ReadFull(rr.r, b); err != nil {
+		if err == io.EOF {
+			err = io.ErrUnexpectedEOF
+		}
+		return nil, err
+	}
+	return b, nil
+}
+
+// mergeSource merges the sorted runs into one sorted Source, the value from
+// the run of the larger order wins for the duplicated keys.
+type mergeSource struct {
+	h runHeap
+}
+
+func newMergeSource(paths []string, compare func(a, b []byte) int) (*mergeSource, error) {
+	ms := &mergeSource{h: runHeap{compare: compare}}
+	for i, path := range paths {
+		rr, err := openRun(path, i)
+		if err != nil {
+			ms.Close()
+			return nil, err
+		}
+		if err := rr.next(); err != nil {
+			rr.f.Close()
+			if err == io.EOF {
+				continue
+			}
+			ms.Close()
+			return nil, err
+		}
+		ms.h.readers = append(ms.h.readers, rr)
+	}
+	heap.Init(&ms.h)
+	return ms, nil
+}
+
+// Next implements Source.
+func (ms *mergeSource) Next() ([]byte, []byte, error) {
+	if ms.h.Len() == 0 {
+		return nil, nil, io.EOF
+	}
+	top := ms.h.readers[0]
+	key, value := top.key, top.value
+	if err := ms.advance(); err != nil {
+		return nil, nil, err
+	}
+	// the runs of the larger order are popped later for the same key.
+	for ms.h.Len() > 0 && ms.h.compare(ms.h.readers[0].key, key) == 0 {
+		value = ms.h.readers[0].value
+		if err := ms.advance(); err != nil {
+			return nil, nil, err
+		}
+	}
+	return key, value, nil
+}
+
+// advance moves the top run to its next key value.
+func (ms *mergeSource) advance() error {
+	top := ms.h.readers[0]
+	err := top.next()
+	if err == io.EOF {
+		top.f.Close()
+		heap.Pop(&ms.h)
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	heap.Fix(&ms.h, 0)
+	return nil
+}
+
+// Close closes all the opened runs.
+func (ms *mergeSource) Close() {
+	for _, rr := range ms.h.readers {
+		rr.f.Close()
+	}
+	ms.h.readers = nil
+}
+
+type runHeap struct {
+	readers []*runReader
+	compare func(a, b []byte) int
+}
+
+func (h *runHeap) Len() int { return len(h.readers) }
+func (h *runHeap) Less(i, j int) bool {
+	c := h.compare(h.readers[i].key, h.readers[j].key)
+	if c == 0 {
+		return h.readers[i].order < h.readers[j].order
+	}
+	return c < 0
+}
+func (h *runHeap) Swap(i, j int) { h.readers[i], h.readers[j] = h.readers[j], h.readers[i] }
+func (h *runHeap) Push(x interface{}) {
+	h.readers = append(h.readers, x.(*runReader))
+}
+func (h *runHeap) Pop() interface{} {
+	n := len(h.readers)
+	rr := h.readers[n-1]
+	h.readers = h.readers[:n-1]
+	return rr
+}
@@ -0,0 +1,307 @@
+package gorocksdb
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	// the interval to check the new writes after the subscription reaches
+	// the end of the logs.
+	subscribePollInterval = 100 * time.Millisecond
+	subscribeChanSize     = 64
+)
+
+var errInvalidResumeToken = errors.New("invalid resume token")
+
+// ChangeGapError is returned by a Subscription if the logs needed by the
+// subscription have been deleted, so some changes are lost. The consumer
+// should resync from a checkpoint or a backup, and subscribe again from
+// its sequence number.
+type ChangeGapError struct {
+	// From is the first sequence number which can not be read.
+	From uint64
+	// First is the first sequence number still in the logs, 0 if unknown.
+	First uint64
+}
+
+func (e *ChangeGapError) Error() string {
+	if e.First == 0 {
+		return fmt.Sprintf("change stream gap: the logs since sequence %v have been deleted", e.From)
+	}
+	return fmt.Sprintf("change stream gap: the logs from sequence %v to %v have been deleted", e.From, e.First-1)
+}
+
+// IsChangeGap returns true if the error is a *ChangeGapError.
+func IsChangeGap(err error) bool {
+	_, ok := err.(*ChangeGapError)
+	return ok
+}
+
+// ResumeToken is the position in the change stream, subscribing with it
+// continues after the change it's got from.
+type ResumeToken struct {
+	// Sequence is the sequence number of the next change.
+	Sequence uint64
+}
+
+// String encodes the token as a string, which is decoded by
+// ParseResumeToken.
+func (t ResumeToken) String() string {
+	return strconv.FormatUint(t.Sequence, 10)
+}
+
+// ParseResumeToken decodes a token encoded by ResumeToken.String.
+func ParseResumeToken(s string) (ResumeToken, error) {
+	seq, err := strconv.ParseUint(strings.TrimSpace(s), 10, 64)
+	if err != nil {
+		return ResumeToken{}, errInvalidResumeToken
+	}
+	return ResumeToken{Sequence: seq}, nil
+}
+
+// FileResumeTokenStore persists a resume token in a file, the file is
+// replaced atomically so a crash never leaves a partial token.
+type FileResumeTokenStore struct {
+	Path string
+}
+
+// Load loads the token. If the file does not exist, ok is false.
+func (s *FileResumeTokenStore) Load() (token ResumeToken, ok bool, err error) {
+	data, err := ioutil.ReadFile(s.Path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return ResumeToken{}, false, nil
+		}
+		return ResumeToken{}, false, err
+	}
+	token, err = ParseResumeToken(string(data))
+	if err != nil {
+		return ResumeToken{}, false, err
+	}
+	return token, true, nil
+}
+
+// Save saves the token.
+func (s *FileResumeTokenStore) Save(token ResumeToken) error {
+	f, err := ioutil.TempFile(filepath.Dir(s.Path), filepath.Base(s.Path)+".tmp")
+	if err != nil {
+		return err
+	}
+	tmp := f.Name()
+	_, err = f.WriteString(token.String())
+	if err == nil {
+		err = f.Sync()
+	}
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Rename(tmp, s.Path)
+	}
+	if err != nil {
+		os.Remove(tmp)
+	}
+	return err
+}
+
+// ChangeRecord is a change read from the logs of the db.
+type ChangeRecord struct {
+	WriteBatchRecord
+	// Sequence is the sequence number of the change. A LogData record or a
+	// transaction marker does not have its own sequence number, and has the
+	// sequence number of the next change in the batch.
+	Sequence uint64
+	// Token is the resume token after the change.
+	Token ResumeToken
+}
+
+// ChangeFilter decides whether a change is delivered by the Subscription.
+type ChangeFilter func(record *ChangeRecord) bool
+
+// Subscription is a stream of the changes written to the db, created by
+// DB.Subscribe.
+type Subscription struct {
+	db     *DB
+	filter ChangeFilter
+	next   uint64
+	ch     chan *ChangeRecord
+	err    error
+}
+
+// Subscribe subscribes the changes written to the db since the sequence
+// number fromSeq, the changes which the filter returns true for are
+// delivered to the channel returned by Subscription.Changes. A nil filter
+// delivers all the changes. The subscription follows the logs across log
+// file rotations until the context is done, the db is closed or an error
+// happens; a *ChangeGapError is reported if the logs have been deleted.
+// The key and value of the delivered records are owned by the records.
+// The db should keep the logs long enough for the consumers by
+// SetWALTtlSeconds or SetWalSizeLimitMb.
+func (db *DB) Subscribe(ctx context.Context, fromSeq uint64, filter ChangeFilter) (*Subscription, error) {
+	if fromSeq == 0 {
+		fromSeq = 1
+	}
+	if !db.IsOpened() {
+		return nil, errDBClosed
+	}
+	s := &Subscription{
+		db:     db,
+		filter: filter,
+		next:   fromSeq,
+		ch:     make(chan *ChangeRecord, subscribeChanSize),
+	}
+	go s.run(ctx)
+	return s, nil
+}
+
+// Changes returns the channel of the changes, which is closed when the
+// subscription ends.
+func (s *Subscription) Changes() <-chan *ChangeRecord {
+	return s.ch
+}
+
+// Err returns the error which ends the subscription after the channel is
+// closed, it's nil if the subscription ends by the context.
+func (s *Subscription) Err() error {
+	return s.err
+}
+
+func (s *Subscription) run(ctx context.Context) {
+	defer close(s.ch)
+	for {
+		if s.db.GetLatestSequenceNumber() >= s.next {
+			if err := s.readLogs(ctx); err != nil {
+				if err != ctx.Err() {
+					s.err = err
+				}
+				return
+			}
+			continue
+		}
+		if !s.db.IsOpened() {
+			s.err = errDBClosed
+			return
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(subscribePollInterval):
+		}
+	}
+}
+
+// readLogs reads the logs since s.next until the end of the logs.
+func (s *Subscription) readLogs(ctx context.Context) error {
+	iter, err := s.db.GetUpdatesSince(s.next)
+	if err != nil {
+		if e, ok := err.(*WALPurgedError); ok {
+			return &ChangeGapError{From: s.next, First: e.First}
+		}
+		return err
+	}
+	// the iterator can not be destroyed after the db is closed.
+	destroy := func() {
+		s.db.RLock()
+		if s.db.opened != 0 {
+			iter.Destroy()
+		}
+		s.db.RUnlock()
+	}
+	for {
+		// check the context on each batch, the batches may be all filtered
+		// out while the writes keep coming.
+		if err := ctx.Err(); err != nil {
+			destroy()
+			return err
+		}
+		s.db.RLock()
+		if s.db.opened == 0 {
+			s.db.RUnlock()
+			return errDBClosed
+		}
+		if !iter.Valid() {
+			err := iter.Err()
+			iter.Destroy()
+			s.db.RUnlock()
+			if err != nil {
+				// the logs may be purged while they are read.
+				if first, e := s.db.firstWALSequence(); e == nil && (first == 0 || first > s.next) {
+					return &ChangeGapError{From: s.next, First: first}
+				}
+			}
+			return err
+		}
+		wb, seq := iter.GetBatch()
+		iter.Next()
+		s.db.RUnlock()
+
+		if seq > s.next {
+			wb.Destroy()
+			destroy()
+			return &ChangeGapError{From: s.next, First: seq}
+		}
+		records, err := s.decodeBatch(wb, seq)
+		wb.Destroy()
+		if err != nil {
+			destroy()
+			return err
+		}
+		for _, record := range records {
+			select {
+			case s.ch <- record:
+			case <-ctx.Done():
+				destroy()
+				return ctx.Err()
+			}
+		}
+	}
+}
+
+// decodeBatch decodes the changes in the batch whose first sequence number
+// is seq, the changes before s.next are skipped.
+func (s *Subscription) decodeBatch(wb *WriteBatch, seq uint64) ([]*ChangeRecord, error) {
+	data := wb.Data()
+	if len(data) < kHeader {
+		return nil, nil
+	}
+	end := seq + uint64(binary.LittleEndian.Uint32(data[8:12])) - 1
+	var records []*ChangeRecord
+	it := wb.NewIterator()
+	for it.Next() {
+		r := it.Record()
+		record := &ChangeRecord{
+			WriteBatchRecord: WriteBatchRecord{
+				Key:            append([]byte(nil), r.Key...),
+				Value:          append([]byte(nil), r.Value...),
+				End:            append([]byte(nil), r.End...),
+				Type:           r.Type,
+				ColumnFamilyID: r.ColumnFamilyID,
+			},
+			Sequence: seq,
+		}
+		if r.Type.HasSequence() {
+			seq++
+		}
+		record.Token = ResumeToken{Sequence: seq}
+		if record.Sequence < s.next || (s.filter != nil && !s.filter(record)) {
+			continue
+		}
+		records = append(records, record)
+	}
+	if err := it.Error(); err != nil {
+		return nil, err
+	}
+	if end >= s.next {
+		s.next = end + 1
+	}
+	return records, nil
+}
@@ -0,0 +1,200 @@
+package gorocksdb
+
+import (
+	"context"
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/facebookgo/ensure"
+)
+
+func TestSubscribe(t *testing.T) {
+	db := newTestDB(t, "TestSubscribe", nil)
+	defer db.Close()
+
+	cf, err := db.CreateColumnFamily(NewDefaultOptions(), "guide")
+	ensure.Nil(t, err)
+	defer cf.Destroy()
+
+	wo := NewDefaultWriteOptions()
+	ensure.Nil(t, db.Put(wo, []byte("key1"), []byte("val1")))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	sub, err := db.Subscribe(ctx, 1, func(record *ChangeRecord) bool {
+		return string(record.Key) != "skip"
+	})
+	ensure.Nil(t, err)
+
+	wb := NewWriteBatch()
+	wb.Put([]byte("skip"), []byte("val"))
+	wb.PutCF(cf, []byte("key2"), []byte("val2"))
+	wb.Delete([]byte("key1"))
+	ensure.Nil(t, db.Write(wo, wb))
+	wb.Destroy()
+
+	record := <-sub.Changes()
+	ensure.DeepEqual(t, record.Type, WriteBatchRecordTypeValue)
+	ensure.DeepEqual(t, record.Key, []byte("key1"))
+	ensure.DeepEqual(t, record.Sequence, uint64(1))
+	ensure.DeepEqual(t, record.Token, ResumeToken{Sequence: 2})
+
+	record = <-sub.Changes()
+	ensure.DeepEqual(t, record.Type, WriteBatchRecordTypeValue)
+	ensure.DeepEqual(t, record.ColumnFamilyID, uint32(1))
+	ensure.DeepEqual(t, record.Key, []byte("key2"))
+	ensure.DeepEqual(t, record.Value, []byte("val2"))
+	ensure.DeepEqual(t, record.Sequence, uint64(3))
+
+	record = <-sub.Changes()
+	ensure.DeepEqual(t, record.Type, WriteBatchRecordTypeDeletion)
+	ensure.DeepEqual(t, record.Key, []byte("key1"))
+	ensure.DeepEqual(t, record.Token, ResumeToken{Sequence: 5})
+
+	cancel()
+	for range sub.Changes() {
+	}
+	ensure.Nil(t, sub.Err())
+
+	// resume in the middle of the batch
+	sub, err = db.Subscribe(context.Background(), 4, nil)
+	ensure.Nil(t, err)
+	record = <-sub.Changes()
+	ensure.DeepEqual(t, record.Key, []byte("key1"))
+	ensure.DeepEqual(t, record.Sequence, uint64(4))
+}
+
+func TestSubscribeFirstBatch(t *testing.T) {
+	db := newTestDB(t, "TestSubscribeFirstBatch", nil)
+	defer db.Close()
+
+	cf, err := db.CreateColumnFamily(NewDefaultOptions(), "guide")
+	ensure.Nil(t, err)
+	defer cf.Destroy()
+
+	// the batch is pending before the subscription, and is the first batch
+	// the subscription reads.
+	wo := NewDefaultWriteOptions()
+	wb := NewWriteBatch()
+	wb.Put([]byte("key1"), []byte("val1"))
+	wb.PutCF(cf, []byte("key2"), []byte("val2"))
+	wb.DeleteRange([]byte("key3"), []byte("key5"))
+	wb.Delete([]byte("key1"))
+	ensure.Nil(t, db.Write(wo, wb))
+	wb.Destroy()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	sub, err := db.Subscribe(ctx, 1, nil)
+	ensure.Nil(t, err)
+
+	expected := []*ChangeRecord{
+		{
+			WriteBatchRecord: WriteBatchRecord{Type: WriteBatchRecordTypeValue, Key: []byte("key1"), Value: []byte("val1")},
+			Sequence:         1,
+			Token:            ResumeToken{Sequence: 2},
+		},
+		{
+			WriteBatchRecord: WriteBatchRecord{Type: WriteBatchRecordTypeValue, ColumnFamilyID: 1, Key: []byte("key2"), Value: []byte("val2")},
+			Sequence:         2,
+			Token:            ResumeToken{Sequence: 3},
+		},
+		{
+			WriteBatchRecord: WriteBatchRecord{Type: WriteBatchRecordTypeRangeDeletion, Key: []byte("key3"), End: []byte("key5")},
+			Sequence:         3,
+			Token:            ResumeToken{Sequence: 4},
+		},
+		{
+			WriteBatchRecord: WriteBatchRecord{Type: WriteBatchRecordTypeDeletion, Key: []byte("key1")},
+			Sequence:         4,
+			Token:            ResumeToken{Sequence: 5},
+		},
+	}
+	for _, e := range expected {
+		record := <-sub.Changes()
+		ensure.DeepEqual(t, record.Type, e.Type)
+		ensure.DeepEqual(t, record.ColumnFamilyID, e.ColumnFamilyID)
+		ensure.DeepEqual(t, string(record.Key), string(e.Key))
+		ensure.DeepEqual(t, string(record.Value), string(e.Value))
+		ensure.DeepEqual(t, string(record.End), string(e.End))
+		ensure.DeepEqual(t, record.Sequence, e.Sequence)
+		ensure.DeepEqual(t, record.Token, e.Token)
+	}
+}
+
+func TestSubscribeGap(t *testing.T) {
+	db := newTestDB(t, "TestSubscribeGap", nil)
+	defer db.Close()
+
+	wo := NewDefaultWriteOptions()
+	ensure.Nil(t, db.Put(wo, []byte("key1"), []byte("val1")))
+	fo := NewDefaultFlushOptions()
+	defer fo.Destroy()
+	ensure.Nil(t, db.Flush(fo))
+	ensure.Nil(t, db.Put(wo, []byte("key2"), []byte("val2")))
+
+	sub, err := db.Subscribe(context.Background(), 1, nil)
+	ensure.Nil(t, err)
+	for range sub.Changes() {
+	}
+	ensure.True(t, IsChangeGap(sub.Err()))
+}
+
+func TestSubscribeCancelWhileFiltered(t *testing.T) {
+	db := newTestDB(t, "TestSubscribeCancelWhileFiltered", nil)
+	defer db.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	sub, err := db.Subscribe(ctx, 1, func(record *ChangeRecord) bool {
+		return false
+	})
+	ensure.Nil(t, err)
+
+	stop := make(chan struct{})
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		wo := NewDefaultWriteOptions()
+		for {
+			select {
+			case <-stop:
+				return
+			default:
+			}
+			if err := db.Put(wo, []byte("key"), []byte("val")); err != nil {
+				return
+			}
+		}
+	}()
+	defer func() {
+		close(stop)
+		<-done
+	}()
+
+	cancel()
+	select {
+	case _, ok := <-sub.Changes():
+		ensure.False(t, ok)
+	case <-time.After(5 * time.Second):
+		t.Fatal("the subscription is not ended by the context")
+	}
+	ensure.Nil(t, sub.Err())
+}
+
+func TestFileResumeTokenStore(t *testing.T) {
+	dir, err := ioutil.TempDir("", "gorocksdb-TestFileResumeTokenStore")
+	ensure.Nil(t, err)
+
+	store := &FileResumeTokenStore{Path: filepath.Join(dir, "token")}
+	_, ok, err := store.Load()
+	ensure.Nil(t, err)
+	ensure.False(t, ok)
+
+	ensure.Nil(t, store.Save(ResumeToken{Sequence: 42}))
+	token, ok, err := store.Load()
+	ensure.Nil(t, err)
+	ensure.True(t, ok)
+	ensure.DeepEqual(t, token, ResumeToken{Sequence: 42})
+}
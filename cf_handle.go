@@ -7,12 +7,22 @@ import "unsafe"
 
 // ColumnFamilyHandle represents a handle to a ColumnFamily.
 type ColumnFamilyHandle struct {
-	c *C.rocksdb_column_family_handle_t
+	c  *C.rocksdb_column_family_handle_t
+	id uint32
 }
 
 // NewNativeColumnFamilyHandle creates a ColumnFamilyHandle object.
 func NewNativeColumnFamilyHandle(c *C.rocksdb_column_family_handle_t) *ColumnFamilyHandle {
-	return &ColumnFamilyHandle{c}
+	h := &ColumnFamilyHandle{c: c}
+	if c != nil {
+		h.id = uint32(C.rocksdb_column_family_handle_get_id(c))
+	}
+	return h
+}
+
+// ID returns the id of the column family, 0 for the default column family.
+func (h *ColumnFamilyHandle) ID() uint32 {
+	return h.id
 }
 
 // UnsafeGetCFHandler returns the underlying c column family handle.
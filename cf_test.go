@@ -19,6 +19,16 @@ func TestColumnFamilyOpen(t *testing.T) {
 	ensure.Nil(t, err)
 	defer db.Close()
 	ensure.DeepEqual(t, len(cfh), 2)
+
+	ro := NewDefaultReadOptions()
+	values, errs := db.MultiGetPinned(ro, [][]byte{[]byte("key1")})
+	ensure.Nil(t, errs[0])
+	ensure.False(t, values[0].Exists())
+	values[0].Destroy()
+	values, errs = db.MultiGetPinnedCF(ro, cfh[0], [][]byte{[]byte("key1")})
+	ensure.Nil(t, errs[0])
+	ensure.False(t, values[0].Exists())
+	values[0].Destroy()
 	cfh[0].Destroy()
 	cfh[1].Destroy()
 
@@ -2,10 +2,12 @@ package gorocksdb
 
 // #include <stdlib.h>
 // #include "rocksdb/c.h"
+// #include "gorocksdb.h"
 import "C"
 import (
 	"errors"
 	"fmt"
+	"path/filepath"
 	"sync"
 	"sync/atomic"
 	"unsafe"
@@ -29,6 +31,50 @@ type DB struct {
 	name   string
 	opts   *Options
 	opened int32
+	// secondary is true if the db is opened by OpenDbAsSecondary.
+	secondary bool
+	// base is true if the db is the base db of an OptimisticTransactionDB,
+	// whose handle is only freed by Close.
+	base bool
+	// resources are the live iterators, snapshots, checkpoints and pinned
+	// slices of the db, which are released by Close. resMu is not the db
+	// lock, so the resources can be tracked and released by the callers
+	// holding the db read lock.
+	resMu     sync.Mutex
+	resources map[dbResource]struct{}
+}
+
+// dbResource is a c object which references the db, it should be released
+// before the db is closed.
+type dbResource interface {
+	// release releases the c object, it's called once with resMu held,
+	// either by Close or by the owner of the resource.
+	release()
+}
+
+// track tracks the resource until it's released, it returns false if the
+// db is closed, and the resource should be released by the caller then.
+func (db *DB) track(r dbResource) bool {
+	db.resMu.Lock()
+	defer db.resMu.Unlock()
+	if !db.IsOpened() {
+		return false
+	}
+	if db.resources == nil {
+		db.resources = make(map[dbResource]struct{})
+	}
+	db.resources[r] = struct{}{}
+	return true
+}
+
+// untrack releases the resource if it's not released by Close yet.
+func (db *DB) untrack(r dbResource) {
+	db.resMu.Lock()
+	defer db.resMu.Unlock()
+	if _, ok := db.resources[r]; ok {
+		delete(db.resources, r)
+		r.release()
+	}
 }
 
 // OpenDb opens a database with the specified options.
@@ -291,6 +337,73 @@ func (db *DB) Exist(opts *ReadOptions, key []byte) (bool, error) {
 	return true, nil
 }
 
+// KeyMayExist checks whether the key may exist in the database by the
+// memtables, the table cache and the bloom filters, without reading the
+// data blocks from the storage. If it returns false, the key definitely
+// does not exist; if true, the key may exist, which should be checked by Get.
+// If fetchValue is true and the value is found in memory, the value is
+// returned, otherwise the returned value is nil.
+func (db *DB) KeyMayExist(opts *ReadOptions, key []byte, fetchValue bool) (bool, []byte, error) {
+	var (
+		cValue      *C.char
+		cValLen     C.size_t
+		cValueFound C.uchar
+		cKey        = byteToChar(key)
+	)
+	db.RLock()
+	if db.opened == 0 {
+		db.RUnlock()
+		return false, nil, errDBClosed
+	}
+
+	var exist C.uchar
+	if fetchValue {
+		exist = C.rocksdb_key_may_exist(db.c, opts.c, cKey, C.size_t(len(key)),
+			&cValue, &cValLen, nil, 0, &cValueFound)
+	} else {
+		exist = C.rocksdb_key_may_exist(db.c, opts.c, cKey, C.size_t(len(key)),
+			&cValue, &cValLen, nil, 0, nil)
+	}
+	db.RUnlock()
+	return exist != 0, keyMayExistValue(cValue, cValLen, cValueFound), nil
+}
+
+// KeyMayExistCF is like KeyMayExist but for the column family.
+func (db *DB) KeyMayExistCF(opts *ReadOptions, cf *ColumnFamilyHandle, key []byte, fetchValue bool) (bool, []byte, error) {
+	var (
+		cValue      *C.char
+		cValLen     C.size_t
+		cValueFound C.uchar
+		cKey        = byteToChar(key)
+	)
+	db.RLock()
+	if db.opened == 0 {
+		db.RUnlock()
+		return false, nil, errDBClosed
+	}
+
+	var exist C.uchar
+	if fetchValue {
+		exist = C.rocksdb_key_may_exist_cf(db.c, opts.c, cf.c, cKey, C.size_t(len(key)),
+			&cValue, &cValLen, nil, 0, &cValueFound)
+	} else {
+		exist = C.rocksdb_key_may_exist_cf(db.c, opts.c, cf.c, cKey, C.size_t(len(key)),
+			&cValue, &cValLen, nil, 0, nil)
+	}
+	db.RUnlock()
+	return exist != 0, keyMayExistValue(cValue, cValLen, cValueFound), nil
+}
+
+// keyMayExistValue copies and frees the value returned by key_may_exist,
+// which is only allocated if the value is found.
+func keyMayExistValue(cValue *C.char, cValLen C.size_t, cValueFound C.uchar) []byte {
+	if cValueFound == 0 || cValue == nil {
+		return nil
+	}
+	defer C.free(unsafe.Pointer(cValue))
+	return C.GoBytes(unsafe.Pointer(cValue), C.int(cValLen))
+}
+
 func (db *DB) ExistNoLock(opts *ReadOptions, key []byte) (bool, error) {
 	var (
 		cErr    *C.char
@@ -383,6 +496,218 @@ func (db *DB) GetCF(opts *ReadOptions, cf *ColumnFamilyHandle, key []byte) (*Sli
 	return NewSlice(cValue, cValLen), nil
 }
 
+// GetPinned returns the data associated with the key from the database
+// without copying it, see PinnableSlice.
+func (db *DB) GetPinned(opts *ReadOptions, key []byte) (*PinnableSlice, error) {
+	var (
+		cErr *C.char
+		cKey = byteToChar(key)
+	)
+	db.RLock()
+	if db.opened == 0 {
+		db.RUnlock()
+		return nil, errDBClosed
+	}
+
+	cHandle := C.rocksdb_get_pinned(db.c, opts.c, cKey, C.size_t(len(key)), &cErr)
+	if cErr != nil {
+		db.RUnlock()
+		defer C.free(unsafe.Pointer(cErr))
+		return nil, errors.New(C.GoString(cErr))
+	}
+	v, err := newDBPinnableSlice(db, cHandle)
+	db.RUnlock()
+	return v, err
+}
+
+// GetPinnedCF returns the data associated with the key from the database
+// and column family without copying it, see PinnableSlice.
+func (db *DB) GetPinnedCF(opts *ReadOptions, cf *ColumnFamilyHandle, key []byte) (*PinnableSlice, error) {
+	var (
+		cErr *C.char
+		cKey = byteToChar(key)
+	)
+	db.RLock()
+	if db.opened == 0 {
+		db.RUnlock()
+		return nil, errDBClosed
+	}
+
+	cHandle := C.rocksdb_get_pinned_cf(db.c, opts.c, cf.c, cKey, C.size_t(len(key)), &cErr)
+	if cErr != nil {
+		db.RUnlock()
+		defer C.free(unsafe.Pointer(cErr))
+		return nil, errors.New(C.GoString(cErr))
+	}
+	v, err := newDBPinnableSlice(db, cHandle)
+	db.RUnlock()
+	return v, err
+}
+
+// MultiGetPinned is like GetPinned for the keys in a single cgo call.
+// The values of the keys not found are empty PinnableSlices.
+// The keys are read one by one, since the batched MultiGet of the C API
+// needs a column family handle and the handle of the default column family
+// can not be got from the db. Use MultiGetPinnedCF with the handle returned
+// by OpenDbColumnFamilies for the batched read.
+func (db *DB) MultiGetPinned(opts *ReadOptions, keyList [][]byte) ([]*PinnableSlice, []error) {
+	return db.multiGetPinned(opts, nil, keyList, false)
+}
+
+// MultiGetPinnedCF is like GetPinnedCF for the keys by the batched MultiGet
+// of rocksdb, which reads the keys in the same data block once.
+// The values of the keys not found are empty PinnableSlices.
+func (db *DB) MultiGetPinnedCF(opts *ReadOptions, cf *ColumnFamilyHandle, keyList [][]byte) ([]*PinnableSlice, []error) {
+	return db.multiGetPinned(opts, cf, keyList, false)
+}
+
+// MultiGetSortedCF is like MultiGetPinnedCF for the keys which are sorted
+// by the comparator of the column family, so rocksdb skips sorting them
+// and coalesces the reads of the adjacent keys.
+func (db *DB) MultiGetSortedCF(opts *ReadOptions, cf *ColumnFamilyHandle, keyList [][]byte) ([]*PinnableSlice, []error) {
+	return db.multiGetPinned(opts, cf, keyList, true)
+}
+
+func (db *DB) multiGetPinned(opts *ReadOptions, cf *ColumnFamilyHandle, keyList [][]byte, sortedInput bool) ([]*PinnableSlice, []error) {
+	n := len(keyList)
+	values := make([]*PinnableSlice, n)
+	errs := make([]error, n)
+	if n == 0 {
+		return values, errs
+	}
+	failAll := func(err error) ([]*PinnableSlice, []error) {
+		for i := range errs {
+			values[i] = NewNativePinnableSlice(nil)
+			errs[i] = err
+		}
+		return values, errs
+	}
+	// the keys are passed in one buffer, since a slice of the pointers
+	// to the go keys can not be passed to c.
+	size := 0
+	for _, k := range keyList {
+		size += len(k)
+	}
+	keys := make([]byte, 0, size)
+	cKeySizeList := make([]C.size_t, n)
+	for i, k := range keyList {
+		keys = append(keys, k...)
+		cKeySizeList[i] = C.size_t(len(k))
+	}
+	cValues := make([]*C.rocksdb_pinnableslice_t, n)
+	cErrs := make([]*C.char, n)
+
+	db.RLock()
+	if db.opened == 0 {
+		db.RUnlock()
+		return failAll(errDBClosed)
+	}
+	if cf == nil {
+		C.gorocksdb_multi_get_pinned(db.c, opts.c, C.size_t(n),
+			byteToChar(keys), &cKeySizeList[0], &cValues[0], &cErrs[0])
+	} else {
+		C.gorocksdb_batched_multi_get_cf(db.c, opts.c, cf.c, C.size_t(n),
+			byteToChar(keys), &cKeySizeList[0], &cValues[0], &cErrs[0], boolToChar(sortedInput))
+	}
+
+	for i := 0; i < n; i++ {
+		if cErrs[i] != nil {
+			errs[i] = errors.New(C.GoString(cErrs[i]))
+			C.free(unsafe.Pointer(cErrs[i]))
+		}
+		// the db is not closed while the read lock is held.
+		values[i], _ = newDBPinnableSlice(db, cValues[i])
+	}
+	db.RUnlock()
+	return values, errs
+}
+
+// MultiGet returns the data associated with the keys from the database.
+// The data of the keys not found are nil.
+func (db *DB) MultiGet(opts *ReadOptions, keyList [][]byte) ([]*Slice, []error) {
+	return db.multiGet(opts, nil, keyList)
+}
+
+// MultiGetCF returns the data associated with the keys from the database,
+// keyList[i] is read from the column family cfs[i].
+// The data of the keys not found are nil.
+func (db *DB) MultiGetCF(opts *ReadOptions, cfs []*ColumnFamilyHandle, keyList [][]byte) ([]*Slice, []error) {
+	if len(cfs) != len(keyList) {
+		return failedSlices(len(keyList), errors.New("must provide the same number of column families and keys"))
+	}
+	for _, cf := range cfs {
+		if cf == nil {
+			return failedSlices(len(keyList), errors.New("column family handle should not be nil"))
+		}
+	}
+	return db.multiGet(opts, cfs, keyList)
+}
+
+// failedSlices returns n empty Slices with the same error, so the caller can
+// free all the values as usual.
+func failedSlices(n int, err error) ([]*Slice, []error) {
+	values := make([]*Slice, n)
+	errs := make([]error, n)
+	for i := range values {
+		values[i] = NewSlice(nil, 0)
+		errs[i] = err
+	}
+	return values, errs
+}
+
+func (db *DB) multiGet(opts *ReadOptions, cfs []*ColumnFamilyHandle, keyList [][]byte) ([]*Slice, []error) {
+	n := len(keyList)
+	values := make([]*Slice, n)
+	errs := make([]error, n)
+	if n == 0 {
+		return values, errs
+	}
+	cKeys := make([]*C.char, n)
+	cKeySizeList := make([]C.size_t, n)
+	cValues := make([]*C.char, n)
+	cValueSizeList := make([]C.size_t, n)
+	cErrs := make([]*C.char, n)
+	for i, k := range keyList {
+		cKeys[i] = cByteSlice(k)
+		cKeySizeList[i] = C.size_t(len(k))
+	}
+	defer func() {
+		for i := range cKeys {
+			C.free(unsafe.Pointer(cKeys[i]))
+		}
+	}()
+	var cCFs []*C.rocksdb_column_family_handle_t
+	if cfs != nil {
+		cCFs = make([]*C.rocksdb_column_family_handle_t, n)
+		for i, cf := range cfs {
+			cCFs[i] = cf.c
+		}
+	}
+
+	db.RLock()
+	if db.opened == 0 {
+		db.RUnlock()
+		return failedSlices(n, errDBClosed)
+	}
+	if cCFs == nil {
+		C.rocksdb_multi_get(db.c, opts.c, C.size_t(n),
+			&cKeys[0], &cKeySizeList[0], &cValues[0], &cValueSizeList[0], &cErrs[0])
+	} else {
+		C.rocksdb_multi_get_cf(db.c, opts.c, &cCFs[0], C.size_t(n),
+			&cKeys[0], &cKeySizeList[0], &cValues[0], &cValueSizeList[0], &cErrs[0])
+	}
+	db.RUnlock()
+
+	for i := 0; i < n; i++ {
+		values[i] = NewSlice(cValues[i], cValueSizeList[i])
+		if cErrs[i] != nil {
+			errs[i] = errors.New(C.GoString(cErrs[i]))
+			C.free(unsafe.Pointer(cErrs[i]))
+		}
+	}
+	return values, errs
+}
+
 func (db *DB) MultiGetBytes(opts *ReadOptions, keyList [][]byte, values [][]byte, errs []error) {
 	cKeys := make([]*C.char, len(keyList))
 	cKeySizeList := make([]C.size_t, len(keyList))
@@ -476,6 +801,9 @@ func (db *DB) ExistCnt(opts *ReadOptions, keyList [][]byte) (int64, error) {
 
 // Put writes data associated with a key to the database.
 func (db *DB) Put(opts *WriteOptions, key, value []byte) error {
+	if err := db.checkWritable("Put"); err != nil {
+		return err
+	}
 	var (
 		cErr   *C.char
 		cKey   = byteToChar(key)
@@ -491,6 +819,9 @@ func (db *DB) Put(opts *WriteOptions, key, value []byte) error {
 
 // PutCF writes data associated with a key to the database and column family.
 func (db *DB) PutCF(opts *WriteOptions, cf *ColumnFamilyHandle, key, value []byte) error {
+	if err := db.checkWritable("PutCF"); err != nil {
+		return err
+	}
 	var (
 		cErr   *C.char
 		cKey   = byteToChar(key)
@@ -506,6 +837,9 @@ func (db *DB) PutCF(opts *WriteOptions, cf *ColumnFamilyHandle, key, value []byt
 
 // Delete removes the data associated with the key from the database.
 func (db *DB) Delete(opts *WriteOptions, key []byte) error {
+	if err := db.checkWritable("Delete"); err != nil {
+		return err
+	}
 	var (
 		cErr *C.char
 		cKey = byteToChar(key)
@@ -520,6 +854,9 @@ func (db *DB) Delete(opts *WriteOptions, key []byte) error {
 
 // DeleteCF removes the data associated with the key from the database and column family.
 func (db *DB) DeleteCF(opts *WriteOptions, cf *ColumnFamilyHandle, key []byte) error {
+	if err := db.checkWritable("DeleteCF"); err != nil {
+		return err
+	}
 	var (
 		cErr *C.char
 		cKey = byteToChar(key)
@@ -534,6 +871,9 @@ func (db *DB) DeleteCF(opts *WriteOptions, cf *ColumnFamilyHandle, key []byte) e
 
 // Merge merges the data associated with the key with the actual data in the database.
 func (db *DB) Merge(opts *WriteOptions, key []byte, value []byte) error {
+	if err := db.checkWritable("Merge"); err != nil {
+		return err
+	}
 	var (
 		cErr   *C.char
 		cKey   = byteToChar(key)
@@ -557,6 +897,9 @@ func (db *DB) Merge(opts *WriteOptions, key []byte, value []byte) error {
 // MergeCF merges the data associated with the key with the actual data in the
 // database and column family.
 func (db *DB) MergeCF(opts *WriteOptions, cf *ColumnFamilyHandle, key []byte, value []byte) error {
+	if err := db.checkWritable("MergeCF"); err != nil {
+		return err
+	}
 	var (
 		cErr   *C.char
 		cKey   = byteToChar(key)
@@ -579,6 +922,9 @@ func (db *DB) MergeCF(opts *WriteOptions, cf *ColumnFamilyHandle, key []byte, va
 
 // Write writes a WriteBatch to the database
 func (db *DB) Write(opts *WriteOptions, batch *WriteBatch) error {
+	if err := db.checkWritable("Write"); err != nil {
+		return err
+	}
 	var cErr *C.char
 	C.rocksdb_write(db.c, opts.c, batch.c, &cErr)
 	if cErr != nil {
@@ -588,15 +934,37 @@ func (db *DB) Write(opts *WriteOptions, batch *WriteBatch) error {
 	return nil
 }
 
+// WriteWithIndex writes a WriteBatchWithIndex to the database
+func (db *DB) WriteWithIndex(opts *WriteOptions, batch *WriteBatchWithIndex) error {
+	if err := db.checkWritable("WriteWithIndex"); err != nil {
+		return err
+	}
+	var cErr *C.char
+	db.RLock()
+	if db.opened == 0 {
+		db.RUnlock()
+		return errDBClosed
+	}
+	C.rocksdb_write_writebatch_wi(db.c, opts.c, batch.c, &cErr)
+	db.RUnlock()
+	if cErr != nil {
+		defer C.free(unsafe.Pointer(cErr))
+		return errors.New(C.GoString(cErr))
+	}
+	return nil
+}
+
 // NewIterator returns an Iterator over the the database that uses the
 // ReadOptions given.
 // iterator should be protected by rlock by caller since it may hold during iterating
+// The iterator is released when the database is closed. The ReadOptions
+// should not be destroyed before the iterator if Refresh is called.
 func (db *DB) NewIterator(opts *ReadOptions) (*Iterator, error) {
 	if db.opened == 0 {
 		return nil, errDBClosed
 	}
 	cIter := C.rocksdb_create_iterator(db.c, opts.c)
-	return NewNativeIterator(unsafe.Pointer(cIter)), nil
+	return newDBIterator(db, cIter, opts, nil)
 }
 
 // NewIteratorCF returns an Iterator over the the database and column family
@@ -606,7 +974,7 @@ func (db *DB) NewIteratorCF(opts *ReadOptions, cf *ColumnFamilyHandle) (*Iterato
 		return nil, errDBClosed
 	}
 	cIter := C.rocksdb_create_iterator_cf(db.c, opts.c, cf.c)
-	return NewNativeIterator(unsafe.Pointer(cIter)), nil
+	return newDBIterator(db, cIter, opts, cf)
 }
 
 // NewSnapshot creates a new snapshot of the database.
@@ -615,7 +983,12 @@ func (db *DB) NewSnapshot() (*Snapshot, error) {
 		return nil, errDBClosed
 	}
 	cSnap := C.rocksdb_create_snapshot(db.c)
-	return NewNativeSnapshot(cSnap, db.c), nil
+	snap := &Snapshot{c: cSnap, cDb: db.c, db: db}
+	if !db.track(snap) {
+		C.rocksdb_release_snapshot(db.c, cSnap)
+		return nil, errDBClosed
+	}
+	return snap, nil
 }
 
 // GetProperty returns the value of a database property.
@@ -653,6 +1026,9 @@ func (db *DB) GetPropertyCF(propName string, cf *ColumnFamilyHandle) string {
 // CreateColumnFamily create a new column family.
 // ColumnFamily should be closed before the engine closed
 func (db *DB) CreateColumnFamily(opts *Options, name string) (*ColumnFamilyHandle, error) {
+	if err := db.checkWritable("CreateColumnFamily"); err != nil {
+		return nil, err
+	}
 	var (
 		cErr  *C.char
 		cName = C.CString(name)
@@ -668,6 +1044,9 @@ func (db *DB) CreateColumnFamily(opts *Options, name string) (*ColumnFamilyHandl
 
 // DropColumnFamily drops a column family.
 func (db *DB) DropColumnFamily(c *ColumnFamilyHandle) error {
+	if err := db.checkWritable("DropColumnFamily"); err != nil {
+		return err
+	}
 	var cErr *C.char
 	C.rocksdb_drop_column_family(db.c, c.c, &cErr)
 	if cErr != nil {
@@ -843,6 +1222,9 @@ func (db *DB) GetApproximateKeyNum(ranges []Range) uint64 {
 }
 
 func (db *DB) SetCFOptions(keys, values []string) error {
+	if err := db.checkWritable("SetCFOptions"); err != nil {
+		return err
+	}
 	db.RLock()
 	defer db.RUnlock()
 	if db.opened == 0 {
@@ -884,6 +1266,9 @@ func (db *DB) SetCFOptions(keys, values []string) error {
 }
 
 func (db *DB) SetDBOptions(keys, values []string) error {
+	if err := db.checkWritable("SetDBOptions"); err != nil {
+		return err
+	}
 	db.RLock()
 	defer db.RUnlock()
 	if db.opened == 0 {
@@ -966,9 +1351,76 @@ func (db *DB) GetLiveFilesMetaData() []LiveFileMetadata {
 	return liveFiles
 }
 
+// WalFileType is the type of a WAL file.
+type WalFileType int
+
+// Types of WAL files.
+const (
+	// WalArchived is a WAL file which is moved to the archive dir and
+	// only kept for GetUpdatesSince.
+	WalArchived = WalFileType(0)
+	// WalAlive is a WAL file which is needed to recover the memtables.
+	WalAlive = WalFileType(1)
+)
+
+// WalFile is a metadata which is associated with each WAL file.
+type WalFile struct {
+	// PathName is relative to the wal dir, like "/archive/000003.log".
+	PathName      string
+	LogNumber     uint64
+	Type          WalFileType
+	StartSequence uint64
+	Size          uint64
+}
+
+// GetSortedWalFiles returns a list of all the alive and archived WAL files
+// sorted by the log number. The files are listed from the wal dir and its
+// archive sub dir, and the start sequence of a file is read from its first
+// write batch, which is 0 if the file is empty.
+func (db *DB) GetSortedWalFiles() ([]WalFile, error) {
+	if !db.IsOpened() {
+		return nil, errDBClosed
+	}
+	files, err := listWALFiles(db.walDir())
+	if err != nil {
+		return nil, err
+	}
+	walFiles := make([]WalFile, len(files))
+	for i, f := range files {
+		seq, err := walStartSequence(f)
+		if err != nil {
+			return nil, fmt.Errorf("read wal %v: %v", f.path, err)
+		}
+		walFiles[i] = WalFile{
+			PathName:      "/" + filepath.Base(f.path),
+			LogNumber:     f.number,
+			Type:          WalAlive,
+			StartSequence: seq,
+			Size:          uint64(f.size),
+		}
+		if f.archived {
+			walFiles[i].PathName = "/archive" + walFiles[i].PathName
+			walFiles[i].Type = WalArchived
+		}
+	}
+	return walFiles, nil
+}
+
+// walDir returns the dir of the write-ahead log files of the db.
+func (db *DB) walDir() string {
+	if db.opts != nil && db.opts.walDir != "" {
+		return db.opts.walDir
+	}
+	return db.name
+}
+
 // CompactRange runs a manual compaction on the Range of keys given. This is
-// not likely to be needed for typical usage.
+// not likely to be needed for typical usage. It silently does nothing on a
+// secondary instance since no error can be returned, see IsSecondary.
 func (db *DB) CompactRange(r Range) {
+	if db.checkWritable("CompactRange") != nil {
+		return
+	}
 	db.RLock()
 	op := db.opened
 	db.RUnlock()
@@ -994,7 +1446,12 @@ func (db *DB) CompactRange(r Range) {
 
 // CompactRangeCF runs a manual compaction on the Range of keys given on the
 // given column family. This is not likely to be needed for typical usage.
+// It silently does nothing on a secondary instance since no error can be
+// returned, see IsSecondary.
 func (db *DB) CompactRangeCF(cf *ColumnFamilyHandle, r Range) {
+	if db.checkWritable("CompactRangeCF") != nil {
+		return
+	}
 	// TODO: fixme about lock
 	db.RLock()
 	if db.opened == 0 {
@@ -1012,6 +1469,9 @@ func (db *DB) CompactRangeCF(cf *ColumnFamilyHandle, r Range) {
 
 // Flush triggers a manuel flush for the database.
 func (db *DB) Flush(opts *FlushOptions) error {
+	if err := db.checkWritable("Flush"); err != nil {
+		return err
+	}
 	var cErr *C.char
 	db.RLock()
 	if db.opened == 0 {
@@ -1028,8 +1488,138 @@ func (db *DB) Flush(opts *FlushOptions) error {
 	return nil
 }
 
+// FlushWAL writes the WAL buffer to the WAL file, which is only needed if
+// the manual WAL flush is enabled by Options.SetManualWALFlush. If sync is
+// true, the WAL file is also synced.
+func (db *DB) FlushWAL(sync bool) error {
+	if err := db.checkWritable("FlushWAL"); err != nil {
+		return err
+	}
+	var cErr *C.char
+	db.RLock()
+	if db.opened == 0 {
+		db.RUnlock()
+		return errDBClosed
+	}
+
+	C.rocksdb_flush_wal(db.c, boolToChar(sync), &cErr)
+	db.RUnlock()
+	if cErr != nil {
+		defer C.free(unsafe.Pointer(cErr))
+		return errors.New(C.GoString(cErr))
+	}
+	return nil
+}
+
+// SyncWAL syncs the WAL file, it's the same as FlushWAL(true). The C API has
+// no separate call for SyncWAL, so the WAL buffer is also written to the
+// file if the manual WAL flush is enabled.
+func (db *DB) SyncWAL() error {
+	if err := db.checkWritable("SyncWAL"); err != nil {
+		return err
+	}
+	var cErr *C.char
+	db.RLock()
+	if db.opened == 0 {
+		db.RUnlock()
+		return errDBClosed
+	}
+
+	C.rocksdb_flush_wal(db.c, 1, &cErr)
+	db.RUnlock()
+	if cErr != nil {
+		defer C.free(unsafe.Pointer(cErr))
+		return errors.New(C.GoString(cErr))
+	}
+	return nil
+}
+
+// IngestExternalFile loads a list of external sst files created by
+// SstFileWriter into the database atomically.
+func (db *DB) IngestExternalFile(filePaths []string, opts *IngestExternalFileOptions) error {
+	if err := db.checkWritable("IngestExternalFile"); err != nil {
+		return err
+	}
+	if len(filePaths) == 0 {
+		return nil
+	}
+	cFilePaths := make([]*C.char, len(filePaths))
+	for i, s := range filePaths {
+		cFilePaths[i] = C.CString(s)
+	}
+	defer func() {
+		for _, s := range cFilePaths {
+			C.free(unsafe.Pointer(s))
+		}
+	}()
+
+	var cErr *C.char
+	db.RLock()
+	if db.opened == 0 {
+		db.RUnlock()
+		return errDBClosed
+	}
+	C.rocksdb_ingest_external_file(
+		db.c,
+		&cFilePaths[0],
+		C.size_t(len(filePaths)),
+		opts.c,
+		&cErr,
+	)
+	db.RUnlock()
+	if cErr != nil {
+		defer C.free(unsafe.Pointer(cErr))
+		return errors.New(C.GoString(cErr))
+	}
+	return nil
+}
+
+// IngestExternalFileCF loads a list of external sst files created by
+// SstFileWriter into the column family atomically.
+func (db *DB) IngestExternalFileCF(cf *ColumnFamilyHandle, filePaths []string, opts *IngestExternalFileOptions) error {
+	if err := db.checkWritable("IngestExternalFileCF"); err != nil {
+		return err
+	}
+	if len(filePaths) == 0 {
+		return nil
+	}
+	cFilePaths := make([]*C.char, len(filePaths))
+	for i, s := range filePaths {
+		cFilePaths[i] = C.CString(s)
+	}
+	defer func() {
+		for _, s := range cFilePaths {
+			C.free(unsafe.Pointer(s))
+		}
+	}()
+
+	var cErr *C.char
+	db.RLock()
+	if db.opened == 0 {
+		db.RUnlock()
+		return errDBClosed
+	}
+	C.rocksdb_ingest_external_file_cf(
+		db.c,
+		cf.c,
+		&cFilePaths[0],
+		C.size_t(len(filePaths)),
+		opts.c,
+		&cErr,
+	)
+	db.RUnlock()
+	if cErr != nil {
+		defer C.free(unsafe.Pointer(cErr))
+		return errors.New(C.GoString(cErr))
+	}
+	return nil
+}
+
 // DisableFileDeletions disables file deletions and should be used when backup the database.
 func (db *DB) DisableFileDeletions() error {
+	if err := db.checkWritable("DisableFileDeletions"); err != nil {
+		return err
+	}
 	var cErr *C.char
 	db.RLock()
 	if db.opened == 0 {
@@ -1048,6 +1638,9 @@ func (db *DB) DisableFileDeletions() error {
 
 // EnableFileDeletions enables file deletions for the database.
 func (db *DB) EnableFileDeletions(force bool) error {
+	if err := db.checkWritable("EnableFileDeletions"); err != nil {
+		return err
+	}
 	var cErr *C.char
 	db.RLock()
 	if db.opened == 0 {
@@ -1065,6 +1658,9 @@ func (db *DB) EnableFileDeletions(force bool) error {
 }
 
 func (db *DB) DeleteFilesInRange(r Range) error {
+	if err := db.checkWritable("DeleteFilesInRange"); err != nil {
+		return err
+	}
 	var (
 		cErr   *C.char
 		cStart = byteToChar(r.Start)
@@ -1081,7 +1677,12 @@ func (db *DB) DeleteFilesInRange(r Range) error {
 // DeleteFile deletes the file name from the db directory and update the internal state to
 // reflect that. Supports deletion of sst and log files only. 'name' must be
 // path relative to the db directory. eg. 000001.sst, /archive/000003.log.
+// It silently does nothing on a secondary instance since no error can be
+// returned, see IsSecondary.
 func (db *DB) DeleteFile(name string) {
+	if db.checkWritable("DeleteFile") != nil {
+		return
+	}
 	cName := C.CString(name)
 	defer C.free(unsafe.Pointer(cName))
 	db.RLock()
@@ -1107,10 +1708,27 @@ func (db *DB) PreShutdown() {
 }
 
 // Close closes the database.
+// The iterators, snapshots, checkpoints and pinned slices of the db are
+// released, and their methods return errDBClosed or behave as invalid after
+// that.
 func (db *DB) Close() {
 	db.Lock()
+	if db.opened == 0 {
+		db.Unlock()
+		return
+	}
 	atomic.StoreInt32(&db.opened, 0)
-	C.rocksdb_close(db.c)
+	db.resMu.Lock()
+	for r := range db.resources {
+		r.release()
+	}
+	db.resources = nil
+	db.resMu.Unlock()
+	if db.base {
+		C.rocksdb_optimistictransactiondb_close_base_db(db.c)
+	} else {
+		C.rocksdb_close(db.c)
+	}
 	db.Unlock()
 }
 
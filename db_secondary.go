@@ -0,0 +1,159 @@
+package gorocksdb
+
+// #include <stdlib.h>
+// #include "rocksdb/c.h"
+import "C"
+import (
+	"errors"
+	"unsafe"
+)
+
+// SecondaryModeError is returned by the write operations of a db opened
+// by OpenDbAsSecondary, which is only able to read. CompactRange,
+// CompactRangeCF and DeleteFile return no error, they silently do nothing
+// on a secondary instance.
+type SecondaryModeError struct {
+	// Op is the name of the operation.
+	Op string
+}
+
+func (e *SecondaryModeError) Error() string {
+	return e.Op + " is not supported in secondary mode"
+}
+
+// IsSecondaryMode returns true if the error is a *SecondaryModeError.
+func IsSecondaryMode(err error) bool {
+	_, ok := err.(*SecondaryModeError)
+	return ok
+}
+
+// checkWritable returns a *SecondaryModeError if the db is a secondary
+// instance.
+func (db *DB) checkWritable(op string) error {
+	if db.secondary {
+		return &SecondaryModeError{Op: op}
+	}
+	return nil
+}
+
+// OpenDbAsSecondary opens a secondary instance of the database at
+// primaryPath, which can be opened by another process as the primary
+// instance. The secondary instance reads the files of the primary and
+// follows it by TryCatchUpWithPrimary; secondaryPath is where the secondary
+// instance keeps its info logs. The max_open_files option should be -1.
+func OpenDbAsSecondary(opts *Options, primaryPath, secondaryPath string) (*DB, error) {
+	var (
+		cErr           *C.char
+		cName          = C.CString(primaryPath)
+		cSecondaryPath = C.CString(secondaryPath)
+	)
+	defer C.free(unsafe.Pointer(cName))
+	defer C.free(unsafe.Pointer(cSecondaryPath))
+	db := C.rocksdb_open_as_secondary(opts.c, cName, cSecondaryPath, &cErr)
+	if cErr != nil {
+		defer C.free(unsafe.Pointer(cErr))
+		return nil, errors.New(C.GoString(cErr))
+	}
+	return &DB{
+		name:      primaryPath,
+		c:         db,
+		opts:      opts,
+		opened:    int32(1),
+		secondary: true,
+	}, nil
+}
+
+// OpenDbAsSecondaryColumnFamilies opens a secondary instance of the
+// database with the specified column families, the default column family
+// should be included.
+func OpenDbAsSecondaryColumnFamilies(
+	opts *Options,
+	primaryPath string,
+	secondaryPath string,
+	cfNames []string,
+	cfOpts []*Options,
+) (*DB, []*ColumnFamilyHandle, error) {
+	numColumnFamilies := len(cfNames)
+	if numColumnFamilies != len(cfOpts) {
+		return nil, nil, errors.New("must provide the same number of column family names and options")
+	}
+
+	cName := C.CString(primaryPath)
+	defer C.free(unsafe.Pointer(cName))
+	cSecondaryPath := C.CString(secondaryPath)
+	defer C.free(unsafe.Pointer(cSecondaryPath))
+
+	cNames := make([]*C.char, numColumnFamilies)
+	for i, s := range cfNames {
+		cNames[i] = C.CString(s)
+	}
+	defer func() {
+		for _, s := range cNames {
+			C.free(unsafe.Pointer(s))
+		}
+	}()
+
+	cOpts := make([]*C.rocksdb_options_t, numColumnFamilies)
+	for i, o := range cfOpts {
+		cOpts[i] = o.c
+	}
+
+	cHandles := make([]*C.rocksdb_column_family_handle_t, numColumnFamilies)
+
+	var cErr *C.char
+	db := C.rocksdb_open_as_secondary_column_families(
+		opts.c,
+		cName,
+		cSecondaryPath,
+		C.int(numColumnFamilies),
+		&cNames[0],
+		&cOpts[0],
+		&cHandles[0],
+		&cErr,
+	)
+	if cErr != nil {
+		defer C.free(unsafe.Pointer(cErr))
+		return nil, nil, errors.New(C.GoString(cErr))
+	}
+
+	cfHandles := make([]*ColumnFamilyHandle, numColumnFamilies)
+	for i, c := range cHandles {
+		cfHandles[i] = NewNativeColumnFamilyHandle(c)
+	}
+
+	return &DB{
+		name:      primaryPath,
+		c:         db,
+		opts:      opts,
+		opened:    int32(1),
+		secondary: true,
+	}, cfHandles, nil
+}
+
+// IsSecondary returns true if the db is opened by OpenDbAsSecondary.
+func (db *DB) IsSecondary() bool {
+	return db.secondary
+}
+
+// TryCatchUpWithPrimary makes the secondary instance catch up with the
+// primary by reading the new manifest and log files of the primary. The
+// iterators and snapshots created before do not see the new data.
+func (db *DB) TryCatchUpWithPrimary() error {
+	if !db.secondary {
+		return errors.New("not a secondary instance")
+	}
+	var cErr *C.char
+	db.RLock()
+	if db.opened == 0 {
+		db.RUnlock()
+		return errDBClosed
+	}
+
+	C.rocksdb_try_catch_up_with_primary(db.c, &cErr)
+	db.RUnlock()
+	if cErr != nil {
+		defer C.free(unsafe.Pointer(cErr))
+		return errors.New(C.GoString(cErr))
+	}
+	return nil
+}
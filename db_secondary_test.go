@@ -0,0 +1,58 @@
+package gorocksdb
+
+import (
+	"io/ioutil"
+	"testing"
+
+	"github.com/facebookgo/ensure"
+)
+
+func TestOpenDbAsSecondary(t *testing.T) {
+	db := newTestDB(t, "TestOpenDbAsSecondary", nil)
+	defer db.Close()
+
+	var (
+		wo = NewDefaultWriteOptions()
+		ro = NewDefaultReadOptions()
+	)
+	ensure.Nil(t, db.Put(wo, []byte("key1"), []byte("val1")))
+
+	secondaryDir, err := ioutil.TempDir("", "gorocksdb-TestOpenDbAsSecondary-secondary")
+	ensure.Nil(t, err)
+	opts := NewDefaultOptions()
+	opts.SetMaxOpenFiles(-1)
+	secondary, err := OpenDbAsSecondary(opts, db.Name(), secondaryDir)
+	ensure.Nil(t, err)
+	defer secondary.Close()
+	ensure.True(t, secondary.IsSecondary())
+
+	v, err := secondary.GetBytes(ro, []byte("key1"))
+	ensure.Nil(t, err)
+	ensure.DeepEqual(t, v, []byte("val1"))
+
+	// the new writes are seen after catching up
+	ensure.Nil(t, db.Put(wo, []byte("key2"), []byte("val2")))
+	v, err = secondary.GetBytes(ro, []byte("key2"))
+	ensure.Nil(t, err)
+	ensure.True(t, v == nil)
+	ensure.Nil(t, secondary.TryCatchUpWithPrimary())
+	v, err = secondary.GetBytes(ro, []byte("key2"))
+	ensure.Nil(t, err)
+	ensure.DeepEqual(t, v, []byte("val2"))
+
+	err = secondary.Put(wo, []byte("key3"), []byte("val3"))
+	ensure.True(t, IsSecondaryMode(err))
+	wb := NewWriteBatch()
+	defer wb.Destroy()
+	wb.Put([]byte("key3"), []byte("val3"))
+	ensure.True(t, IsSecondaryMode(secondary.Write(wo, wb)))
+	err = secondary.SetDBOptions([]string{"max_background_jobs"}, []string{"4"})
+	ensure.True(t, IsSecondaryMode(err))
+	err = secondary.SetCFOptions([]string{"disable_auto_compactions"}, []string{"true"})
+	ensure.True(t, IsSecondaryMode(err))
+	ensure.True(t, IsSecondaryMode(secondary.DisableFileDeletions()))
+	ensure.True(t, IsSecondaryMode(secondary.EnableFileDeletions(false)))
+	// these return nothing, but should not reach the secondary instance.
+	secondary.CompactRange(Range{})
+	secondary.DeleteFile("/000001.sst")
+}
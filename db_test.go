@@ -2,6 +2,8 @@ package gorocksdb
 
 import (
 	"io/ioutil"
+	"path/filepath"
+	"strings"
 	"testing"
 
 	"github.com/facebookgo/ensure"
@@ -69,7 +71,199 @@ func TestDBCRUD(t *testing.T) {
 	ensure.True(t, v3.Data() == nil)
 }
 
-func newTestDB(t *testing.T, name string, applyOpts func(opts *Options)) *DB {
+func TestDBKeyMayExist(t *testing.T) {
+	db := newTestDB(t, "TestDBKeyMayExist", nil)
+	defer db.Close()
+
+	var (
+		wo = NewDefaultWriteOptions()
+		ro = NewDefaultReadOptions()
+	)
+	ensure.Nil(t, db.Put(wo, []byte("key1"), []byte("val1")))
+
+	// the value in the memtable is found
+	exist, v, err := db.KeyMayExist(ro, []byte("key1"), true)
+	ensure.Nil(t, err)
+	ensure.True(t, exist)
+	ensure.DeepEqual(t, v, []byte("val1"))
+
+	exist, v, err = db.KeyMayExist(ro, []byte("key1"), false)
+	ensure.Nil(t, err)
+	ensure.True(t, exist)
+	ensure.True(t, v == nil)
+
+	exist, _, err = db.KeyMayExist(ro, []byte("key2"), true)
+	ensure.Nil(t, err)
+	ensure.False(t, exist)
+}
+
+func TestDBGetPinned(t *testing.T) {
+	db := newTestDB(t, "TestDBGetPinned", nil)
+	defer db.Close()
+
+	cf, err := db.CreateColumnFamily(NewDefaultOptions(), "guide")
+	ensure.Nil(t, err)
+	defer cf.Destroy()
+
+	var (
+		wo = NewDefaultWriteOptions()
+		ro = NewDefaultReadOptions()
+	)
+	ensure.Nil(t, db.Put(wo, []byte("key1"), []byte("val1")))
+	ensure.Nil(t, db.PutCF(wo, cf, []byte("key2"), []byte("val2")))
+
+	v, err := db.GetPinned(ro, []byte("key1"))
+	ensure.Nil(t, err)
+	ensure.True(t, v.Exists())
+	ensure.DeepEqual(t, v.Data(), []byte("val1"))
+	v.Destroy()
+
+	v, err = db.GetPinned(ro, []byte("key2"))
+	ensure.Nil(t, err)
+	ensure.False(t, v.Exists())
+	ensure.True(t, v.Data() == nil)
+	v.Destroy()
+
+	v, err = db.GetPinnedCF(ro, cf, []byte("key2"))
+	ensure.Nil(t, err)
+	ensure.DeepEqual(t, v.Data(), []byte("val2"))
+	v.Destroy()
+
+	values, errs := db.MultiGetPinned(ro, [][]byte{[]byte("key1"), []byte("key2")})
+	ensure.Nil(t, errs[0])
+	ensure.Nil(t, errs[1])
+	ensure.DeepEqual(t, values[0].Data(), []byte("val1"))
+	ensure.False(t, values[1].Exists())
+	for _, v := range values {
+		v.Destroy()
+	}
+
+	values, errs = db.MultiGetPinnedCF(ro, cf, [][]byte{[]byte("key1"), []byte("key2")})
+	ensure.Nil(t, errs[0])
+	ensure.Nil(t, errs[1])
+	ensure.False(t, values[0].Exists())
+	ensure.DeepEqual(t, values[1].Data(), []byte("val2"))
+	for _, v := range values {
+		v.Destroy()
+	}
+}
+
+func TestDBGetPinnedClose(t *testing.T) {
+	db := newTestDB(t, "TestDBGetPinnedClose", nil)
+
+	var (
+		wo = NewDefaultWriteOptions()
+		ro = NewDefaultReadOptions()
+	)
+	ensure.Nil(t, db.Put(wo, []byte("key1"), []byte("val1")))
+	v, err := db.GetPinned(ro, []byte("key1"))
+	ensure.Nil(t, err)
+	values, errs := db.MultiGetPinned(ro, [][]byte{[]byte("key1")})
+	ensure.Nil(t, errs[0])
+
+	// the slices are released by closing the db, destroying them after
+	// that does nothing.
+	db.Close()
+	ensure.True(t, v.Data() == nil)
+	ensure.True(t, values[0].Data() == nil)
+	v.Destroy()
+	values[0].Destroy()
+	v.Destroy()
+
+	_, err = db.GetPinned(ro, []byte("key1"))
+	ensure.DeepEqual(t, err, errDBClosed)
+}
+
+func TestDBMultiGetCF(t *testing.T) {
+	db := newTestDB(t, "TestDBMultiGetCF", nil)
+	defer db.Close()
+
+	cf, err := db.CreateColumnFamily(NewDefaultOptions(), "guide")
+	ensure.Nil(t, err)
+	defer cf.Destroy()
+
+	var (
+		wo = NewDefaultWriteOptions()
+		ro = NewDefaultReadOptions()
+	)
+	ensure.Nil(t, db.Put(wo, []byte("key1"), []byte("val1")))
+	ensure.Nil(t, db.PutCF(wo, cf, []byte("key2"), []byte("val2")))
+	ensure.Nil(t, db.PutCF(wo, cf, []byte("key3"), []byte("val3")))
+
+	values, errs := db.MultiGet(ro, [][]byte{[]byte("key1"), []byte("key2")})
+	ensure.Nil(t, errs[0])
+	ensure.Nil(t, errs[1])
+	ensure.DeepEqual(t, values[0].Data(), []byte("val1"))
+	ensure.True(t, values[1].Data() == nil)
+	for _, v := range values {
+		v.Free()
+	}
+
+	values, errs = db.MultiGetCF(ro,
+		[]*ColumnFamilyHandle{cf, cf},
+		[][]byte{[]byte("key1"), []byte("key2")})
+	ensure.Nil(t, errs[0])
+	ensure.Nil(t, errs[1])
+	ensure.True(t, values[0].Data() == nil)
+	ensure.DeepEqual(t, values[1].Data(), []byte("val2"))
+	for _, v := range values {
+		v.Free()
+	}
+
+	// the values of the rejected calls can be freed too.
+	values, errs = db.MultiGetCF(ro, []*ColumnFamilyHandle{cf}, [][]byte{[]byte("key1"), []byte("key2")})
+	ensure.NotNil(t, errs[0])
+	for _, v := range values {
+		v.Free()
+	}
+	values, errs = db.MultiGetCF(ro, []*ColumnFamilyHandle{cf, nil}, [][]byte{[]byte("key1"), []byte("key2")})
+	ensure.NotNil(t, errs[1])
+	for _, v := range values {
+		v.Free()
+	}
+
+	pinned, errs := db.MultiGetSortedCF(ro, cf, [][]byte{[]byte("key1"), []byte("key2"), []byte("key3")})
+	ensure.Nil(t, errs[0])
+	ensure.False(t, pinned[0].Exists())
+	ensure.DeepEqual(t, pinned[1].Data(), []byte("val2"))
+	ensure.DeepEqual(t, pinned[2].Data(), []byte("val3"))
+	for _, v := range pinned {
+		v.Destroy()
+	}
+}
+
+func TestDBGetSortedWalFiles(t *testing.T) {
+	db := newTestDB(t, "TestDBGetSortedWalFiles", func(opts *Options) {
+		opts.SetWALTtlSeconds(3600)
+		opts.SetManualWALFlush(true)
+	})
+	defer db.Close()
+
+	wo := NewDefaultWriteOptions()
+	ensure.Nil(t, db.Put(wo, []byte("key1"), []byte("val1")))
+	ensure.Nil(t, db.FlushWAL(false))
+	fo := NewDefaultFlushOptions()
+	defer fo.Destroy()
+	ensure.Nil(t, db.Flush(fo))
+	ensure.Nil(t, db.Put(wo, []byte("key2"), []byte("val2")))
+	ensure.Nil(t, db.FlushWAL(true))
+	ensure.Nil(t, db.Put(wo, []byte("key3"), []byte("val3")))
+	ensure.Nil(t, db.SyncWAL())
+
+	files, err := db.GetSortedWalFiles()
+	ensure.Nil(t, err)
+	ensure.DeepEqual(t, len(files), 2)
+	ensure.DeepEqual(t, files[0].Type, WalArchived)
+	ensure.DeepEqual(t, files[0].StartSequence, uint64(1))
+	ensure.DeepEqual(t, files[1].Type, WalAlive)
+	ensure.DeepEqual(t, files[1].StartSequence, uint64(2))
+	ensure.True(t, files[0].LogNumber < files[1].LogNumber)
+	ensure.True(t, files[1].Size > 0)
+	ensure.True(t, strings.HasPrefix(files[0].PathName, "/archive/"))
+	ensure.DeepEqual(t, filepath.Dir(files[1].PathName), "/")
+}
+
+func newTestDB(t testing.TB, name string, applyOpts func(opts *Options)) *DB {
 	dir, err := ioutil.TempDir("", "gorocksdb-"+name)
 	ensure.Nil(t, err)
 
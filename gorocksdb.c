@@ -1,3 +1,4 @@
+#include <string.h>
 #include "gorocksdb.h"
 #include "_cgo_export.h"
 
@@ -68,3 +69,62 @@ rocksdb_slicetransform_t* gorocksdb_slicetransform_create(uintptr_t idx) {
     	(unsigned char (*)(void*, const char*, size_t))(gorocksdb_slicetransform_in_range),
     	(const char* (*)(void*))(gorocksdb_slicetransform_name));
 }
+
+/* Write Batch */
+
+void gorocksdb_write_raw(rocksdb_t* db, const rocksdb_writeoptions_t* options, const char* rep, size_t size, char** errptr) {
+    rocksdb_writebatch_t* b = rocksdb_writebatch_create_from(rep, size);
+    rocksdb_write(db, options, b, errptr);
+    rocksdb_writebatch_destroy(b);
+}
+
+/* DB */
+
+void gorocksdb_multi_get_pinned(rocksdb_t* db, const rocksdb_readoptions_t* options, size_t num_keys, const char* keys, const size_t* keys_list_sizes, rocksdb_pinnableslice_t** values, char** errs) {
+    size_t off = 0;
+    for (size_t i = 0; i < num_keys; i++) {
+        values[i] = rocksdb_get_pinned(db, options, keys + off, keys_list_sizes[i], &errs[i]);
+        off += keys_list_sizes[i];
+    }
+}
+
+void gorocksdb_batched_multi_get_cf(rocksdb_t* db, const rocksdb_readoptions_t* options, rocksdb_column_family_handle_t* column_family, size_t num_keys, const char* keys, const size_t* keys_list_sizes, rocksdb_pinnableslice_t** values, char** errs, unsigned char sorted_input) {
+    const char** keys_list = (const char**)malloc(num_keys * sizeof(char*));
+    size_t off = 0;
+    for (size_t i = 0; i < num_keys; i++) {
+        keys_list[i] = keys + off;
+        off += keys_list_sizes[i];
+    }
+    rocksdb_batched_multi_get_cf(db, options, column_family, num_keys, keys_list, keys_list_sizes, values, errs, sorted_input);
+    free(keys_list);
+}
+
+/* Iterator */
+
+size_t gorocksdb_iter_next_batch(rocksdb_iterator_t* iter, unsigned char reverse, size_t max_entries, char* buf, size_t buf_size, size_t* sizes, size_t* need) {
+    size_t n = 0, off = 0;
+    *need = 0;
+    while (n < max_entries && rocksdb_iter_valid(iter)) {
+        size_t klen, vlen;
+        const char* key = rocksdb_iter_key(iter, &klen);
+        const char* val = rocksdb_iter_value(iter, &vlen);
+        if (off + klen + vlen > buf_size) {
+            if (n == 0) {
+                *need = klen + vlen;
+            }
+            break;
+        }
+        memcpy(buf + off, key, klen);
+        memcpy(buf + off + klen, val, vlen);
+        off += klen + vlen;
+        sizes[2 * n] = klen;
+        sizes[2 * n + 1] = vlen;
+        n++;
+        if (reverse) {
+            rocksdb_iter_prev(iter);
+        } else {
+            rocksdb_iter_next(iter);
+        }
+    }
+    return n;
+}
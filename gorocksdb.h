@@ -28,3 +28,16 @@ extern void gorocksdb_mergeoperator_delete_value(void* state, const char* v, siz
 /* Slice Transform */
 
 extern rocksdb_slicetransform_t* gorocksdb_slicetransform_create(uintptr_t idx);
+
+/* Write Batch */
+
+extern void gorocksdb_write_raw(rocksdb_t* db, const rocksdb_writeoptions_t* options, const char* rep, size_t size, char** errptr);
+
+/* DB */
+
+extern void gorocksdb_multi_get_pinned(rocksdb_t* db, const rocksdb_readoptions_t* options, size_t num_keys, const char* keys, const size_t* keys_list_sizes, rocksdb_pinnableslice_t** values, char** errs);
+extern void gorocksdb_batched_multi_get_cf(rocksdb_t* db, const rocksdb_readoptions_t* options, rocksdb_column_family_handle_t* column_family, size_t num_keys, const char* keys, const size_t* keys_list_sizes, rocksdb_pinnableslice_t** values, char** errs, unsigned char sorted_input);
+
+/* Iterator */
+
+extern size_t gorocksdb_iter_next_batch(rocksdb_iterator_t* iter, unsigned char reverse, size_t max_entries, char* buf, size_t buf_size, size_t* sizes, size_t* need);
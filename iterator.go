@@ -2,13 +2,17 @@ package gorocksdb
 
 // #include <stdlib.h>
 // #include "rocksdb/c.h"
+// #include "gorocksdb.h"
 import "C"
 import (
 	"bytes"
 	"errors"
+	"sync/atomic"
 	"unsafe"
 )
 
+var errIteratorNotRefreshable = errors.New("only the iterators on a db can be refreshed")
+
 // Iterator provides a way to seek to specific keys and iterate through
 // the keyspace from that point, as well as access the values of those keys.
 //
@@ -28,34 +32,79 @@ import (
 //
 type Iterator struct {
 	c *C.rocksdb_iterator_t
+	// db is the db the iterator is created from, the iterator is released
+	// when the db is closed. It's nil if the iterator is not on a db.
+	db *DB
+	// released is set once the iterator is released by closing the db.
+	released int32
+	// ro and cf are the read options and the column family the iterator is
+	// created with on the db, used by Refresh.
+	ro *ReadOptions
+	cf *ColumnFamilyHandle
+	// the buffers of NextBatch and PrevBatch, reused across the calls.
+	batchBuf    []byte
+	batchSizes  []C.size_t
+	batchKeys   [][]byte
+	batchValues [][]byte
 }
 
 // NewNativeIterator creates a Iterator object.
 func NewNativeIterator(c unsafe.Pointer) *Iterator {
-	return &Iterator{(*C.rocksdb_iterator_t)(c)}
+	return &Iterator{c: (*C.rocksdb_iterator_t)(c)}
+}
+
+// newDBIterator creates a Iterator object on the db, the caller should hold
+// the db read lock like NewIterator. cf is nil for the default column family.
+func newDBIterator(db *DB, c *C.rocksdb_iterator_t, ro *ReadOptions, cf *ColumnFamilyHandle) (*Iterator, error) {
+	iter := &Iterator{c: c, db: db, ro: ro, cf: cf}
+	if !db.track(iter) {
+		C.rocksdb_iter_destroy(c)
+		return nil, errDBClosed
+	}
+	return iter, nil
+}
+
+func (iter *Iterator) release() {
+	atomic.StoreInt32(&iter.released, 1)
+	C.rocksdb_iter_destroy(iter.c)
+}
+
+// closed returns true if the iterator is released by closing the db. The
+// check is lock-free and only reliable if the caller holds the db read lock
+// while using the iterator, so the db is not closed meanwhile. Without the
+// lock, the db may still be closed right after the check and the iterator
+// used after it's released, so the lock is required if the db may be
+// closed concurrently.
+func (iter *Iterator) closed() bool {
+	return atomic.LoadInt32(&iter.released) != 0
 }
 
 // Valid returns false only when an Iterator has iterated past either the
-// first or the last key in the database.
+// first or the last key in the database, or the database is closed.
 func (iter *Iterator) Valid() bool {
+	if iter.closed() {
+		return false
+	}
 	return C.rocksdb_iter_valid(iter.c) != 0
 }
 
 // ValidForPrefix returns false only when an Iterator has iterated past the
 // first or the last key in the database or the specified prefix.
 func (iter *Iterator) ValidForPrefix(prefix []byte) bool {
-	if C.rocksdb_iter_valid(iter.c) == 0 {
+	if iter.closed() || C.rocksdb_iter_valid(iter.c) == 0 {
 		return false
 	}
 
-	key := iter.Key()
-	result := bytes.HasPrefix(key.Data(), prefix)
-	key.Free()
-	return result
+	var cLen C.size_t
+	cKey := C.rocksdb_iter_key(iter.c, &cLen)
+	return bytes.HasPrefix(charToByte(cKey, cLen), prefix)
 }
 
 // Key returns the key the iterator currently holds.
 func (iter *Iterator) Key() *Slice {
+	if iter.closed() {
+		return nil
+	}
 	var cLen C.size_t
 	cKey := C.rocksdb_iter_key(iter.c, &cLen)
 	if cKey == nil {
@@ -66,6 +115,9 @@ func (iter *Iterator) Key() *Slice {
 
 // Value returns the value in the database the iterator currently holds.
 func (iter *Iterator) Value() *Slice {
+	if iter.closed() {
+		return nil
+	}
 	var cLen C.size_t
 	cVal := C.rocksdb_iter_value(iter.c, &cLen)
 	if cVal == nil {
@@ -76,28 +128,38 @@ func (iter *Iterator) Value() *Slice {
 
 // Next moves the iterator to the next sequential key in the database.
 func (iter *Iterator) Next() {
-	C.rocksdb_iter_next(iter.c)
+	if !iter.closed() {
+		C.rocksdb_iter_next(iter.c)
+	}
 }
 
 // Prev moves the iterator to the previous sequential key in the database.
 func (iter *Iterator) Prev() {
-	C.rocksdb_iter_prev(iter.c)
+	if !iter.closed() {
+		C.rocksdb_iter_prev(iter.c)
+	}
 }
 
 // SeekToFirst moves the iterator to the first key in the database.
 func (iter *Iterator) SeekToFirst() {
-	C.rocksdb_iter_seek_to_first(iter.c)
+	if !iter.closed() {
+		C.rocksdb_iter_seek_to_first(iter.c)
+	}
 }
 
 // SeekToLast moves the iterator to the last key in the database.
 func (iter *Iterator) SeekToLast() {
-	C.rocksdb_iter_seek_to_last(iter.c)
+	if !iter.closed() {
+		C.rocksdb_iter_seek_to_last(iter.c)
+	}
 }
 
 // Seek moves the iterator to the position greater than or equal to the key.
 func (iter *Iterator) Seek(key []byte) {
 	cKey := byteToChar(key)
-	C.rocksdb_iter_seek(iter.c, cKey, C.size_t(len(key)))
+	if !iter.closed() {
+		C.rocksdb_iter_seek(iter.c, cKey, C.size_t(len(key)))
+	}
 }
 
 // seek to the last key that less than or equal to the target key
@@ -105,12 +167,76 @@ func (iter *Iterator) Seek(key []byte) {
 // of the prefix range. use this seekforprev instead
 func (iter *Iterator) SeekForPrev(key []byte) {
 	cKey := byteToChar(key)
-	C.rocksdb_iter_seek_for_prev(iter.c, cKey, C.size_t(len(key)))
+	if !iter.closed() {
+		C.rocksdb_iter_seek_for_prev(iter.c, cKey, C.size_t(len(key)))
+	}
+}
+
+// NextBatch reads up to maxEntries entries from the current position forward
+// and moves the iterator past them, like calling Key, Value and Next in a
+// loop but in a single cgo call. The entries are copied into one buffer of
+// maxBytes, an entry larger than maxBytes is returned alone. The buffer and
+// the returned slices are reused by the next NextBatch or PrevBatch call, so
+// they are only valid until then, copy the entries to keep them. No entry is
+// returned if the iterator is not valid, and Err should be checked then.
+func (iter *Iterator) NextBatch(maxEntries, maxBytes int) (keys, values [][]byte) {
+	return iter.nextBatch(false, maxEntries, maxBytes)
+}
+
+// PrevBatch is like NextBatch but moves the iterator backward.
+func (iter *Iterator) PrevBatch(maxEntries, maxBytes int) (keys, values [][]byte) {
+	return iter.nextBatch(true, maxEntries, maxBytes)
+}
+
+func (iter *Iterator) nextBatch(reverse bool, maxEntries, maxBytes int) (keys, values [][]byte) {
+	if iter.closed() || maxEntries <= 0 {
+		return nil, nil
+	}
+	if maxBytes <= 0 {
+		maxBytes = 1
+	}
+	if cap(iter.batchBuf) < maxBytes {
+		iter.batchBuf = make([]byte, maxBytes)
+	}
+	if cap(iter.batchSizes) < 2*maxEntries {
+		iter.batchSizes = make([]C.size_t, 2*maxEntries)
+	}
+	var (
+		cReverse = boolToChar(reverse)
+		buf      = iter.batchBuf[:maxBytes]
+		sizes    = iter.batchSizes[:2*maxEntries]
+		cNeed    C.size_t
+	)
+	n := C.gorocksdb_iter_next_batch(iter.c, cReverse, C.size_t(maxEntries),
+		(*C.char)(unsafe.Pointer(&buf[0])), C.size_t(len(buf)), &sizes[0], &cNeed)
+	if n == 0 && cNeed > 0 {
+		// the current entry does not fit in maxBytes, the larger buffer is
+		// not kept for the next calls.
+		buf = make([]byte, int(cNeed))
+		n = C.gorocksdb_iter_next_batch(iter.c, cReverse, 1,
+			(*C.char)(unsafe.Pointer(&buf[0])), C.size_t(len(buf)), &sizes[0], &cNeed)
+	}
+	keys = iter.batchKeys[:0]
+	values = iter.batchValues[:0]
+	off := 0
+	for i := 0; i < int(n); i++ {
+		klen, vlen := int(sizes[2*i]), int(sizes[2*i+1])
+		keys = append(keys, buf[off:off+klen:off+klen])
+		off += klen
+		values = append(values, buf[off:off+vlen:off+vlen])
+		off += vlen
+	}
+	iter.batchKeys, iter.batchValues = keys, values
+	return keys, values
 }
 
 // Err returns nil if no errors happened during iteration, or the actual
-// error otherwise.
+// error otherwise. It returns errDBClosed if the iterator is released by
+// closing the database.
 func (iter *Iterator) Err() error {
+	if iter.closed() {
+		return errDBClosed
+	}
 	var cErr *C.char
 	C.rocksdb_iter_get_error(iter.c, &cErr)
 	if cErr != nil {
@@ -120,8 +246,54 @@ func (iter *Iterator) Err() error {
 	return nil
 }
 
-// Close closes the iterator.
+// Refresh rebases the iterator onto the latest state of the db. The C API
+// has no refresh, so a new iterator is created with the ReadOptions given to
+// NewIterator or NewIteratorCF, which should not be destroyed before, and
+// the old one is destroyed. If the iterator is valid, the new one seeks to
+// the current key, so it's positioned at the first key at or after that key,
+// otherwise it's not valid until a seek. If the read options have a
+// snapshot, the iterator still reads the snapshot.
+// Like the other methods, it should be protected by rlock by caller.
+func (iter *Iterator) Refresh() error {
+	if iter.db == nil {
+		return errIteratorNotRefreshable
+	}
+	var key []byte
+	valid := iter.Valid()
+	if valid {
+		var cLen C.size_t
+		cKey := C.rocksdb_iter_key(iter.c, &cLen)
+		key = C.GoBytes(unsafe.Pointer(cKey), C.int(cLen))
+	}
+
+	// resMu keeps the iterator from being released by Close meanwhile.
+	db := iter.db
+	db.resMu.Lock()
+	defer db.resMu.Unlock()
+	if iter.closed() {
+		return errDBClosed
+	}
+	var c *C.rocksdb_iterator_t
+	if iter.cf != nil {
+		c = C.rocksdb_create_iterator_cf(db.c, iter.ro.c, iter.cf.c)
+	} else {
+		c = C.rocksdb_create_iterator(db.c, iter.ro.c)
+	}
+	C.rocksdb_iter_destroy(iter.c)
+	iter.c = c
+	if valid {
+		C.rocksdb_iter_seek(iter.c, byteToChar(key), C.size_t(len(key)))
+	}
+	return nil
+}
+
+// Close closes the iterator, it does nothing if the iterator is released by
+// closing the db.
 func (iter *Iterator) Close() {
+	if iter.db != nil {
+		iter.db.untrack(iter)
+		return
+	}
 	C.rocksdb_iter_destroy(iter.c)
 	iter.c = nil
 }
@@ -1,6 +1,7 @@
 package gorocksdb
 
 import (
+	"fmt"
 	"testing"
 
 	"github.com/facebookgo/ensure"
@@ -30,3 +31,183 @@ func TestIterator(t *testing.T) {
 	ensure.Nil(t, iter.Err())
 	ensure.DeepEqual(t, actualKeys, givenKeys)
 }
+
+func TestIteratorNextBatch(t *testing.T) {
+	db := newTestDB(t, "TestIteratorNextBatch", nil)
+	defer db.Close()
+
+	wo := NewDefaultWriteOptions()
+	ensure.Nil(t, db.Put(wo, []byte("key1"), []byte("val1")))
+	ensure.Nil(t, db.Put(wo, []byte("key2"), []byte("a large value")))
+	ensure.Nil(t, db.Put(wo, []byte("key3"), []byte("val3")))
+
+	ro := NewDefaultReadOptions()
+	iter, err := db.NewIterator(ro)
+	ensure.Nil(t, err)
+	defer iter.Close()
+
+	iter.SeekToFirst()
+	keys, values := iter.NextBatch(10, 10)
+	ensure.DeepEqual(t, keys, [][]byte{[]byte("key1")})
+	ensure.DeepEqual(t, values, [][]byte{[]byte("val1")})
+	// larger than maxBytes
+	keys, values = iter.NextBatch(10, 10)
+	ensure.DeepEqual(t, keys, [][]byte{[]byte("key2")})
+	ensure.DeepEqual(t, values, [][]byte{[]byte("a large value")})
+	keys, _ = iter.NextBatch(10, 1024)
+	ensure.DeepEqual(t, keys, [][]byte{[]byte("key3")})
+	keys, _ = iter.NextBatch(10, 1024)
+	ensure.DeepEqual(t, len(keys), 0)
+	ensure.Nil(t, iter.Err())
+
+	iter.SeekToLast()
+	keys, values = iter.PrevBatch(2, 1024)
+	ensure.DeepEqual(t, keys, [][]byte{[]byte("key3"), []byte("key2")})
+	ensure.DeepEqual(t, values, [][]byte{[]byte("val3"), []byte("a large value")})
+	ensure.True(t, iter.Valid())
+}
+
+func BenchmarkIteratorNext(b *testing.B) {
+	db := newBenchmarkIteratorDB(b, "BenchmarkIteratorNext")
+	defer db.Close()
+	ro := NewDefaultReadOptions()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		iter, _ := db.NewIterator(ro)
+		for iter.SeekToFirst(); iter.Valid(); iter.Next() {
+			k, v := iter.Key(), iter.Value()
+			k.Free()
+			v.Free()
+		}
+		iter.Close()
+	}
+}
+
+func BenchmarkIteratorNextBatch(b *testing.B) {
+	db := newBenchmarkIteratorDB(b, "BenchmarkIteratorNextBatch")
+	defer db.Close()
+	ro := NewDefaultReadOptions()
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		iter, _ := db.NewIterator(ro)
+		iter.SeekToFirst()
+		for {
+			keys, _ := iter.NextBatch(256, 64<<10)
+			if len(keys) == 0 {
+				break
+			}
+		}
+		iter.Close()
+	}
+}
+
+func newBenchmarkIteratorDB(b *testing.B, name string) *DB {
+	db := newTestDB(b, name, nil)
+	wb := NewWriteBatchBuilder(0)
+	for i := 0; i < 10000; i++ {
+		wb.Put([]byte(fmt.Sprintf("key%08d", i)), []byte("value"))
+	}
+	ensure.Nil(b, db.WriteBuilder(NewDefaultWriteOptions(), wb))
+	return db
+}
+
+func TestIteratorAfterDBClose(t *testing.T) {
+	db := newTestDB(t, "TestIteratorAfterDBClose", nil)
+
+	wo := NewDefaultWriteOptions()
+	ensure.Nil(t, db.Put(wo, []byte("key1"), []byte("val1")))
+
+	ro := NewDefaultReadOptions()
+	iter, err := db.NewIterator(ro)
+	ensure.Nil(t, err)
+	snap, err := db.NewSnapshot()
+	ensure.Nil(t, err)
+	checkpoint, err := NewCheckpoint(db)
+	ensure.Nil(t, err)
+	iter.SeekToFirst()
+	ensure.True(t, iter.Valid())
+
+	db.Close()
+
+	ensure.False(t, iter.Valid())
+	iter.Next()
+	ensure.True(t, iter.Key() == nil)
+	ensure.DeepEqual(t, iter.Err(), errDBClosed)
+	ensure.DeepEqual(t, iter.Refresh(), errDBClosed)
+	iter.Close()
+	snap.Release()
+	ensure.DeepEqual(t, checkpoint.Save(db.Name()+"-checkpoint", 0), errDBClosed)
+	checkpoint.Destroy()
+}
+
+func TestIteratorRefresh(t *testing.T) {
+	db := newTestDB(t, "TestIteratorRefresh", nil)
+	defer db.Close()
+
+	wo := NewDefaultWriteOptions()
+	ensure.Nil(t, db.Put(wo, []byte("key1"), []byte("val1")))
+	ensure.Nil(t, db.Put(wo, []byte("key3"), []byte("val3")))
+
+	ro := NewDefaultReadOptions()
+	defer ro.Destroy()
+	iter, err := db.NewIterator(ro)
+	ensure.Nil(t, err)
+	defer iter.Close()
+	iter.SeekToFirst()
+	ensure.DeepEqual(t, iter.Key().Data(), []byte("key1"))
+
+	ensure.Nil(t, db.Put(wo, []byte("key2"), []byte("val2")))
+	ensure.Nil(t, db.Delete(wo, []byte("key1")))
+	iter.Next()
+	ensure.DeepEqual(t, iter.Key().Data(), []byte("key3"))
+
+	// the refreshed iterator sees the writes, and stays at the current key.
+	ensure.Nil(t, iter.Refresh())
+	ensure.DeepEqual(t, iter.Key().Data(), []byte("key3"))
+	iter.SeekToFirst()
+	ensure.DeepEqual(t, iter.Key().Data(), []byte("key2"))
+	iter.Next()
+	ensure.DeepEqual(t, iter.Key().Data(), []byte("key3"))
+
+	native := NewNativeIterator(nil)
+	ensure.DeepEqual(t, native.Refresh(), errIteratorNotRefreshable)
+}
+
+func TestIteratorCallerHoldsLock(t *testing.T) {
+	db := newTestDB(t, "TestIteratorCallerHoldsLock", nil)
+
+	wo := NewDefaultWriteOptions()
+	ensure.Nil(t, db.Put(wo, []byte("key1"), []byte("val1")))
+
+	// the iterators and the snapshots do not take the read lock again, so
+	// they don't deadlock with a Close waiting for the write lock. Close is
+	// started before they are created, it can't return before RUnlock.
+	db.RLock()
+	closing := make(chan struct{})
+	closed := make(chan struct{})
+	go func() {
+		close(closing)
+		db.Close()
+		close(closed)
+	}()
+	<-closing
+	ro := NewDefaultReadOptions()
+	snap, err := db.NewSnapshot()
+	ensure.Nil(t, err)
+	ro.SetSnapshot(snap)
+	iter, err := db.NewIterator(ro)
+	ensure.Nil(t, err)
+	iter.SeekToFirst()
+	ensure.True(t, iter.Valid())
+	ensure.DeepEqual(t, iter.Key().Data(), []byte("key1"))
+	iter.Close()
+	snap.Release()
+	select {
+	case <-closed:
+		t.Fatal("the db is closed while the read lock is held")
+	default:
+	}
+	db.RUnlock()
+	<-closed
+}
@@ -0,0 +1,320 @@
+package gorocksdb
+
+import (
+	"bytes"
+	"container/heap"
+)
+
+// DuplicatePolicy decides which entries a MergedIterator returns for a key
+// which is in more than one source.
+type DuplicatePolicy int
+
+const (
+	// DuplicateKeepAll returns the entries of all the sources, in the order
+	// of the sources when iterating forward.
+	DuplicateKeepAll = DuplicatePolicy(0)
+	// DuplicateFirstWins returns only the entry of the source with the
+	// smallest index.
+	DuplicateFirstWins = DuplicatePolicy(1)
+	// DuplicateLastWins returns only the entry of the source with the
+	// largest index.
+	DuplicateLastWins = DuplicatePolicy(2)
+)
+
+// MergedIterator combines the iterators over several column families or
+// dbs into one ordered iterator by a k-way merge. The sources should be
+// ordered by the same comparator as the MergedIterator.
+// The MergedIterator owns the sources, which should not be used directly
+// and are closed by Close.
+type MergedIterator struct {
+	iters   []*Iterator
+	cmp     func(a, b []byte) int
+	policy  DuplicatePolicy
+	keys    [][]byte
+	h       mergedIteratorHeap
+	reverse bool
+	cur     int
+}
+
+// NewMergedIterator creates a MergedIterator over the sources, a nil cmp
+// compares the keys bytewise like the default comparator of rocksdb.
+// The iterator is not valid until it's positioned by a seek.
+func NewMergedIterator(iters []*Iterator, cmp Comparator, policy DuplicatePolicy) *MergedIterator {
+	m := &MergedIterator{
+		iters:  iters,
+		cmp:    bytes.Compare,
+		policy: policy,
+		keys:   make([][]byte, len(iters)),
+		cur:    -1,
+	}
+	if cmp != nil {
+		m.cmp = cmp.Compare
+	}
+	m.h.m = m
+	return m
+}
+
+// mergedIteratorHeap is the heap of the valid sources, the top is the
+// source of the next entry in the current direction.
+type mergedIteratorHeap struct {
+	m       *MergedIterator
+	sources []int
+}
+
+func (h *mergedIteratorHeap) Len() int { return len(h.sources) }
+
+func (h *mergedIteratorHeap) Less(i, j int) bool {
+	a, b := h.sources[i], h.sources[j]
+	c := h.m.cmp(h.m.keys[a], h.m.keys[b])
+	if c == 0 {
+		// the entries with the same key are in the order of the sources when
+		// iterating forward, and in the reverse order when iterating backward.
+		if h.m.reverse {
+			return a > b
+		}
+		return a < b
+	}
+	if h.m.reverse {
+		return c > 0
+	}
+	return c < 0
+}
+
+func (h *mergedIteratorHeap) Swap(i, j int) {
+	h.sources[i], h.sources[j] = h.sources[j], h.sources[i]
+}
+
+func (h *mergedIteratorHeap) Push(x interface{}) {
+	h.sources = append(h.sources, x.(int))
+}
+
+func (h *mergedIteratorHeap) Pop() interface{} {
+	n := len(h.sources)
+	x := h.sources[n-1]
+	h.sources = h.sources[:n-1]
+	return x
+}
+
+// load caches the current key of the source i, nil if it's not valid.
+func (m *MergedIterator) load(i int) {
+	m.keys[i] = nil
+	if m.iters[i].Valid() {
+		if key := m.iters[i].Key(); key != nil {
+			m.keys[i] = key.Data()
+		}
+	}
+}
+
+// rebuild reloads all the sources after they are moved.
+func (m *MergedIterator) rebuild() {
+	m.h.sources = m.h.sources[:0]
+	for i := range m.iters {
+		m.load(i)
+		if m.keys[i] != nil {
+			m.h.sources = append(m.h.sources, i)
+		}
+	}
+	heap.Init(&m.h)
+	m.setCurrent()
+}
+
+// setCurrent picks the source of the current entry from the heap.
+func (m *MergedIterator) setCurrent() {
+	if m.h.Len() == 0 {
+		m.cur = -1
+		return
+	}
+	m.cur = m.h.sources[0]
+	if m.policy == DuplicateKeepAll {
+		return
+	}
+	for _, i := range m.h.sources {
+		if m.cmp(m.keys[i], m.keys[m.cur]) != 0 {
+			continue
+		}
+		if (m.policy == DuplicateFirstWins && i < m.cur) ||
+			(m.policy == DuplicateLastWins && i > m.cur) {
+			m.cur = i
+		}
+	}
+}
+
+// move moves the source i forward or backward by the current direction.
+func (m *MergedIterator) move(i int) {
+	if m.reverse {
+		m.iters[i].Prev()
+	} else {
+		m.iters[i].Next()
+	}
+}
+
+// advance moves past the current entry in the current direction.
+func (m *MergedIterator) advance() {
+	if m.policy == DuplicateKeepAll {
+		// the current source is the top of the heap.
+		m.move(m.cur)
+		m.load(m.cur)
+		if m.keys[m.cur] == nil {
+			heap.Pop(&m.h)
+		} else {
+			heap.Fix(&m.h, 0)
+		}
+		m.setCurrent()
+		return
+	}
+	// move all the sources with the current key, whose entries are hidden.
+	key := append([]byte(nil), m.keys[m.cur]...)
+	for _, i := range m.h.sources {
+		if m.cmp(m.keys[i], key) == 0 {
+			m.move(i)
+		}
+	}
+	m.rebuild()
+}
+
+// Valid returns false when the MergedIterator has iterated past either the
+// first or the last entry of all the sources.
+func (m *MergedIterator) Valid() bool {
+	return m.cur >= 0
+}
+
+// Key returns the key of the current entry, which is valid until the
+// iterator is moved.
+func (m *MergedIterator) Key() []byte {
+	if m.cur < 0 {
+		return nil
+	}
+	return m.keys[m.cur]
+}
+
+// Value returns the value of the current entry, which is valid until the
+// iterator is moved.
+func (m *MergedIterator) Value() []byte {
+	if m.cur < 0 {
+		return nil
+	}
+	value := m.iters[m.cur].Value()
+	if value == nil {
+		return nil
+	}
+	return value.Data()
+}
+
+// Source returns the index of the source of the current entry, or -1 if
+// the iterator is not valid.
+func (m *MergedIterator) Source() int {
+	return m.cur
+}
+
+// SeekToFirst moves the iterator to the first entry of all the sources.
+func (m *MergedIterator) SeekToFirst() {
+	m.reverse = false
+	for _, iter := range m.iters {
+		iter.SeekToFirst()
+	}
+	m.rebuild()
+}
+
+// SeekToLast moves the iterator to the last entry of all the sources.
+func (m *MergedIterator) SeekToLast() {
+	m.reverse = true
+	for _, iter := range m.iters {
+		iter.SeekToLast()
+	}
+	m.rebuild()
+}
+
+// Seek moves the iterator to the first entry whose key is greater than or
+// equal to the key.
+func (m *MergedIterator) Seek(key []byte) {
+	m.reverse = false
+	for _, iter := range m.iters {
+		iter.Seek(key)
+	}
+	m.rebuild()
+}
+
+// SeekForPrev moves the iterator to the last entry whose key is less than
+// or equal to the key.
+func (m *MergedIterator) SeekForPrev(key []byte) {
+	m.reverse = true
+	for _, iter := range m.iters {
+		iter.SeekForPrev(key)
+	}
+	m.rebuild()
+}
+
+// Next moves the iterator to the next entry.
+func (m *MergedIterator) Next() {
+	if m.cur < 0 {
+		return
+	}
+	if m.reverse {
+		m.switchDirection()
+		return
+	}
+	m.advance()
+}
+
+// Prev moves the iterator to the previous entry.
+func (m *MergedIterator) Prev() {
+	if m.cur < 0 {
+		return
+	}
+	if !m.reverse {
+		m.switchDirection()
+		return
+	}
+	m.advance()
+}
+
+// switchDirection reverses the direction and moves to the entry next to the
+// current entry in the new direction. The sources other than the current
+// one are positioned after the current entry in the old direction, so they
+// are re-seeked to the current key and moved past the entries which are
+// before the current entry in the new direction.
+func (m *MergedIterator) switchDirection() {
+	key := append([]byte(nil), m.keys[m.cur]...)
+	cur := m.cur
+	m.reverse = !m.reverse
+	for i, iter := range m.iters {
+		if i == cur {
+			continue
+		}
+		if m.reverse {
+			iter.SeekForPrev(key)
+		} else {
+			iter.Seek(key)
+		}
+		m.load(i)
+		if m.keys[i] == nil || m.cmp(m.keys[i], key) != 0 {
+			continue
+		}
+		// with DuplicateKeepAll, the entries with the current key are in the
+		// order of the sources.
+		if m.policy != DuplicateKeepAll ||
+			(!m.reverse && i < cur) || (m.reverse && i > cur) {
+			m.move(i)
+		}
+	}
+	m.move(cur)
+	m.rebuild()
+}
+
+// Err returns the first error of the sources.
+func (m *MergedIterator) Err() error {
+	for _, iter := range m.iters {
+		if err := iter.Err(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Close closes all the sources.
+func (m *MergedIterator) Close() {
+	for _, iter := range m.iters {
+		iter.Close()
+	}
+	m.cur = -1
+}
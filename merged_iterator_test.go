@@ -0,0 +1,120 @@
+package gorocksdb
+
+import (
+	"testing"
+
+	"github.com/facebookgo/ensure"
+)
+
+type mergedEntry struct {
+	key, value string
+	source     int
+}
+
+func newTestMergedIterator(t *testing.T, policy DuplicatePolicy) (*MergedIterator, func()) {
+	db1 := newTestDB(t, "TestMergedIterator1", nil)
+	db2 := newTestDB(t, "TestMergedIterator2", nil)
+	cf, err := db1.CreateColumnFamily(NewDefaultOptions(), "guide")
+	ensure.Nil(t, err)
+
+	wo := NewDefaultWriteOptions()
+	ensure.Nil(t, db1.Put(wo, []byte("a"), []byte("0a")))
+	ensure.Nil(t, db1.Put(wo, []byte("c"), []byte("0c")))
+	ensure.Nil(t, db1.PutCF(wo, cf, []byte("b"), []byte("1b")))
+	ensure.Nil(t, db1.PutCF(wo, cf, []byte("c"), []byte("1c")))
+	ensure.Nil(t, db2.Put(wo, []byte("c"), []byte("2c")))
+	ensure.Nil(t, db2.Put(wo, []byte("d"), []byte("2d")))
+
+	ro := NewDefaultReadOptions()
+	iter0, err := db1.NewIterator(ro)
+	ensure.Nil(t, err)
+	iter1, err := db1.NewIteratorCF(ro, cf)
+	ensure.Nil(t, err)
+	iter2, err := db2.NewIterator(ro)
+	ensure.Nil(t, err)
+	m := NewMergedIterator([]*Iterator{iter0, iter1, iter2}, nil, policy)
+	return m, func() {
+		m.Close()
+		cf.Destroy()
+		db1.Close()
+		db2.Close()
+	}
+}
+
+func mergedEntries(m *MergedIterator, reverse bool) []mergedEntry {
+	var entries []mergedEntry
+	for m.Valid() {
+		entries = append(entries, mergedEntry{string(m.Key()), string(m.Value()), m.Source()})
+		if reverse {
+			m.Prev()
+		} else {
+			m.Next()
+		}
+	}
+	return entries
+}
+
+func TestMergedIterator(t *testing.T) {
+	m, closeAll := newTestMergedIterator(t, DuplicateKeepAll)
+	defer closeAll()
+
+	m.SeekToFirst()
+	ensure.DeepEqual(t, mergedEntries(m, false), []mergedEntry{
+		{"a", "0a", 0}, {"b", "1b", 1}, {"c", "0c", 0}, {"c", "1c", 1}, {"c", "2c", 2}, {"d", "2d", 2},
+	})
+	ensure.Nil(t, m.Err())
+
+	m.SeekToLast()
+	ensure.DeepEqual(t, mergedEntries(m, true), []mergedEntry{
+		{"d", "2d", 2}, {"c", "2c", 2}, {"c", "1c", 1}, {"c", "0c", 0}, {"b", "1b", 1}, {"a", "0a", 0},
+	})
+
+	m.Seek([]byte("bb"))
+	ensure.DeepEqual(t, m.Key(), []byte("c"))
+	ensure.DeepEqual(t, m.Source(), 0)
+
+	m.SeekForPrev([]byte("bb"))
+	ensure.DeepEqual(t, m.Key(), []byte("b"))
+
+	// switch the direction in the middle of the duplicates
+	m.Seek([]byte("c"))
+	m.Next()
+	ensure.DeepEqual(t, m.Source(), 1)
+	m.Prev()
+	ensure.DeepEqual(t, m.Key(), []byte("c"))
+	ensure.DeepEqual(t, m.Source(), 0)
+	m.Prev()
+	ensure.DeepEqual(t, m.Key(), []byte("b"))
+	m.Next()
+	ensure.DeepEqual(t, m.Key(), []byte("c"))
+	ensure.DeepEqual(t, m.Source(), 0)
+}
+
+func TestMergedIteratorDuplicatePolicy(t *testing.T) {
+	m, closeAll := newTestMergedIterator(t, DuplicateFirstWins)
+	defer closeAll()
+
+	m.SeekToFirst()
+	ensure.DeepEqual(t, mergedEntries(m, false), []mergedEntry{
+		{"a", "0a", 0}, {"b", "1b", 1}, {"c", "0c", 0}, {"d", "2d", 2},
+	})
+	m.SeekToLast()
+	ensure.DeepEqual(t, mergedEntries(m, true), []mergedEntry{
+		{"d", "2d", 2}, {"c", "0c", 0}, {"b", "1b", 1}, {"a", "0a", 0},
+	})
+
+	m.Seek([]byte("c"))
+	m.Prev()
+	ensure.DeepEqual(t, m.Key(), []byte("b"))
+	m.Next()
+	ensure.DeepEqual(t, m.Source(), 0)
+	m.Next()
+	ensure.DeepEqual(t, m.Key(), []byte("d"))
+
+	m2, closeAll2 := newTestMergedIterator(t, DuplicateLastWins)
+	defer closeAll2()
+	m2.SeekToFirst()
+	ensure.DeepEqual(t, mergedEntries(m2, false), []mergedEntry{
+		{"a", "0a", 0}, {"b", "1b", 1}, {"c", "2c", 2}, {"d", "2d", 2},
+	})
+}
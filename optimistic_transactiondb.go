@@ -0,0 +1,195 @@
+package gorocksdb
+
+// #include <stdlib.h>
+// #include "rocksdb/c.h"
+import "C"
+import (
+	"errors"
+	"sync"
+	"sync/atomic"
+	"unsafe"
+)
+
+// OptimisticTransactionDB is a reusable handle to a RocksDB database with
+// optimistic transaction support, created by OpenOptimisticTransactionDb.
+// No locks are taken by the transactions, the conflicts are detected at
+// commit time and returned as *ConflictError.
+type OptimisticTransactionDB struct {
+	// lock protect the read from closed engine, transactions begun
+	// from this db hold the read lock during each operation.
+	sync.RWMutex
+	c      *C.rocksdb_optimistictransactiondb_t
+	name   string
+	opts   *Options
+	opened int32
+	// base is the db returned by GetBaseDb, which is closed with the db.
+	baseOnce sync.Once
+	base     *DB
+}
+
+// OpenOptimisticTransactionDb opens a optimistic transactional database with
+// the specified options.
+func OpenOptimisticTransactionDb(opts *Options, name string) (*OptimisticTransactionDB, error) {
+	var (
+		cErr  *C.char
+		cName = C.CString(name)
+	)
+	defer C.free(unsafe.Pointer(cName))
+	db := C.rocksdb_optimistictransactiondb_open(opts.c, cName, &cErr)
+	if cErr != nil {
+		defer C.free(unsafe.Pointer(cErr))
+		return nil, errors.New(C.GoString(cErr))
+	}
+	return &OptimisticTransactionDB{
+		name:   name,
+		c:      db,
+		opts:   opts,
+		opened: int32(1),
+	}, nil
+}
+
+// OpenOptimisticTransactionDbColumnFamilies opens a optimistic transactional
+// database with the specified column families.
+func OpenOptimisticTransactionDbColumnFamilies(
+	opts *Options,
+	name string,
+	cfNames []string,
+	cfOpts []*Options,
+) (*OptimisticTransactionDB, []*ColumnFamilyHandle, error) {
+	numColumnFamilies := len(cfNames)
+	if numColumnFamilies != len(cfOpts) {
+		return nil, nil, errors.New("must provide the same number of column family names and options")
+	}
+
+	cName := C.CString(name)
+	defer C.free(unsafe.Pointer(cName))
+
+	cNames := make([]*C.char, numColumnFamilies)
+	for i, s := range cfNames {
+		cNames[i] = C.CString(s)
+	}
+	defer func() {
+		for _, s := range cNames {
+			C.free(unsafe.Pointer(s))
+		}
+	}()
+
+	cOpts := make([]*C.rocksdb_options_t, numColumnFamilies)
+	for i, o := range cfOpts {
+		cOpts[i] = o.c
+	}
+
+	cHandles := make([]*C.rocksdb_column_family_handle_t, numColumnFamilies)
+
+	var cErr *C.char
+	db := C.rocksdb_optimistictransactiondb_open_column_families(
+		opts.c,
+		cName,
+		C.int(numColumnFamilies),
+		&cNames[0],
+		&cOpts[0],
+		&cHandles[0],
+		&cErr,
+	)
+	if cErr != nil {
+		defer C.free(unsafe.Pointer(cErr))
+		return nil, nil, errors.New(C.GoString(cErr))
+	}
+
+	cfHandles := make([]*ColumnFamilyHandle, numColumnFamilies)
+	for i, c := range cHandles {
+		cfHandles[i] = NewNativeColumnFamilyHandle(c)
+	}
+
+	return &OptimisticTransactionDB{
+		name:   name,
+		c:      db,
+		opts:   opts,
+		opened: int32(1),
+	}, cfHandles, nil
+}
+
+// Name returns the name of the database.
+func (db *OptimisticTransactionDB) Name() string {
+	return db.name
+}
+
+func (db *OptimisticTransactionDB) IsOpened() bool {
+	return atomic.LoadInt32(&db.opened) != 0
+}
+
+// TransactionBegin begins a new optimistic transaction with the WriteOptions
+// and OptimisticTransactionOptions given. If oldTxn is not nil, it is reused
+// instead of allocating a new transaction.
+func (db *OptimisticTransactionDB) TransactionBegin(opts *WriteOptions, txnOpts *OptimisticTransactionOptions, oldTxn *Transaction) (*Transaction, error) {
+	db.RLock()
+	defer db.RUnlock()
+	if db.opened == 0 {
+		return nil, errDBClosed
+	}
+	if oldTxn != nil {
+		cTxn := C.rocksdb_optimistictransaction_begin(db.c, opts.c, txnOpts.c, oldTxn.c)
+		oldTxn.c = cTxn
+		oldTxn.db = db
+		return oldTxn, nil
+	}
+	cTxn := C.rocksdb_optimistictransaction_begin(db.c, opts.c, txnOpts.c, nil)
+	return newTransaction(cTxn, db), nil
+}
+
+// GetBaseDb returns the DB the transactions are built on, whose reads and
+// writes bypass the transactions, e.g. the reads are not tracked for the
+// conflicts. The same DB is returned for each call, it's closed when the
+// OptimisticTransactionDB is closed and closing it before only frees the
+// handle.
+func (db *OptimisticTransactionDB) GetBaseDb() (*DB, error) {
+	db.RLock()
+	defer db.RUnlock()
+	if db.opened == 0 {
+		return nil, errDBClosed
+	}
+	db.baseOnce.Do(func() {
+		db.base = &DB{
+			c:      C.rocksdb_optimistictransactiondb_get_base_db(db.c),
+			name:   db.name,
+			opts:   db.opts,
+			opened: int32(1),
+			base:   true,
+		}
+	})
+	return db.base, nil
+}
+
+// Write writes a WriteBatch to the database outside of any transaction.
+func (db *OptimisticTransactionDB) Write(opts *WriteOptions, batch *WriteBatch) error {
+	var cErr *C.char
+	db.RLock()
+	if db.opened == 0 {
+		db.RUnlock()
+		return errDBClosed
+	}
+	C.rocksdb_optimistictransactiondb_write(db.c, opts.c, batch.c, &cErr)
+	db.RUnlock()
+	if cErr != nil {
+		defer C.free(unsafe.Pointer(cErr))
+		return errors.New(C.GoString(cErr))
+	}
+	return nil
+}
+
+// Close closes the database. It waits for the in-flight transaction
+// operations and all the transactions should be committed or rolled back
+// before.
+func (db *OptimisticTransactionDB) Close() {
+	db.Lock()
+	if db.opened == 0 {
+		db.Unlock()
+		return
+	}
+	atomic.StoreInt32(&db.opened, 0)
+	if db.base != nil {
+		db.base.Close()
+	}
+	C.rocksdb_optimistictransactiondb_close(db.c)
+	db.Unlock()
+}
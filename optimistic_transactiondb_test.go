@@ -0,0 +1,126 @@
+package gorocksdb
+
+import (
+	"io/ioutil"
+	"testing"
+
+	"github.com/facebookgo/ensure"
+)
+
+func TestOptimisticTransactionDBCommit(t *testing.T) {
+	db := newTestOptimisticTransactionDB(t, "TestOptimisticTransactionDBCommit", nil)
+	defer db.Close()
+
+	var (
+		givenKey = []byte("hello")
+		givenVal = []byte("world")
+		wo       = NewDefaultWriteOptions()
+		ro       = NewDefaultReadOptions()
+		to       = NewDefaultOptimisticTransactionOptions()
+	)
+
+	txn, err := db.TransactionBegin(wo, to, nil)
+	ensure.Nil(t, err)
+	defer txn.Destroy()
+	ensure.Nil(t, txn.Put(givenKey, givenVal))
+	ensure.Nil(t, txn.Commit())
+
+	txn, err = db.TransactionBegin(wo, to, txn)
+	ensure.Nil(t, err)
+	v, err := txn.Get(ro, givenKey)
+	defer v.Free()
+	ensure.Nil(t, err)
+	ensure.DeepEqual(t, v.Data(), givenVal)
+	ensure.Nil(t, txn.Commit())
+}
+
+func TestOptimisticTransactionDBConflict(t *testing.T) {
+	db := newTestOptimisticTransactionDB(t, "TestOptimisticTransactionDBConflict", nil)
+	defer db.Close()
+
+	var (
+		givenKey  = []byte("hello")
+		givenVal1 = []byte("world1")
+		givenVal2 = []byte("world2")
+		wo        = NewDefaultWriteOptions()
+		ro        = NewDefaultReadOptions()
+		to        = NewDefaultOptimisticTransactionOptions()
+	)
+	to.SetSetSnapshot(true)
+
+	txn1, err := db.TransactionBegin(wo, to, nil)
+	ensure.Nil(t, err)
+	defer txn1.Destroy()
+	snap := txn1.GetSnapshot()
+	ensure.NotNil(t, snap)
+	snap.Release()
+	txn2, err := db.TransactionBegin(wo, to, nil)
+	ensure.Nil(t, err)
+	defer txn2.Destroy()
+
+	v, err := txn1.GetForUpdate(ro, givenKey)
+	ensure.Nil(t, err)
+	v.Free()
+	ensure.Nil(t, txn1.Put(givenKey, givenVal1))
+	ensure.Nil(t, txn2.Put(givenKey, givenVal2))
+	ensure.Nil(t, txn2.Commit())
+
+	err = txn1.Commit()
+	ensure.NotNil(t, err)
+	ensure.True(t, IsConflict(err))
+	ensure.Nil(t, txn1.Rollback())
+}
+
+func TestOptimisticTransactionDBBaseDb(t *testing.T) {
+	db := newTestOptimisticTransactionDB(t, "TestOptimisticTransactionDBBaseDb", nil)
+
+	var (
+		givenKey = []byte("hello")
+		givenVal = []byte("world")
+		wo       = NewDefaultWriteOptions()
+		ro       = NewDefaultReadOptions()
+		to       = NewDefaultOptimisticTransactionOptions()
+	)
+
+	base, err := db.GetBaseDb()
+	ensure.Nil(t, err)
+	ensure.Nil(t, base.Put(wo, givenKey, givenVal))
+
+	// the read of the base db is not tracked by the transaction, so the
+	// later write of the key is not a conflict.
+	txn, err := db.TransactionBegin(wo, to, nil)
+	ensure.Nil(t, err)
+	defer txn.Destroy()
+	v, err := base.GetBytes(ro, givenKey)
+	ensure.Nil(t, err)
+	ensure.DeepEqual(t, v, givenVal)
+	ensure.Nil(t, txn.Put([]byte("other"), givenVal))
+	ensure.Nil(t, base.Put(wo, givenKey, []byte("world2")))
+	ensure.Nil(t, txn.Commit())
+
+	v, err = base.GetBytes(ro, givenKey)
+	ensure.Nil(t, err)
+	ensure.DeepEqual(t, v, []byte("world2"))
+
+	db.Close()
+	db.Close()
+	_, err = base.GetBytes(ro, givenKey)
+	ensure.DeepEqual(t, err, errDBClosed)
+	_, err = db.GetBaseDb()
+	ensure.DeepEqual(t, err, errDBClosed)
+}
+
+func newTestOptimisticTransactionDB(t *testing.T, name string, applyOpts func(opts *Options)) *OptimisticTransactionDB {
+	dir, err := ioutil.TempDir("", "gorocksdb-"+name)
+	ensure.Nil(t, err)
+
+	opts := NewDefaultOptions()
+	opts.SetCreateIfMissing(true)
+	if applyOpts != nil {
+		applyOpts(opts)
+	}
+	db, err := OpenOptimisticTransactionDb(opts, dir)
+	ensure.Nil(t, err)
+
+	return db
+}
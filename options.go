@@ -72,6 +72,9 @@ type Options struct {
 	//cst  *C.rocksdb_slicetransform_t
 
 	ccf *C.rocksdb_compactionfilter_t
+
+	// walDir is kept to list the wal files of the db opened with it.
+	walDir string
 }
 
 // NewDefaultOptions creates the default Options.
@@ -514,6 +517,7 @@ func (opts *Options) SetWalDir(value string) {
 	cvalue := C.CString(value)
 	defer C.free(unsafe.Pointer(cvalue))
 	C.rocksdb_options_set_wal_dir(opts.c, cvalue)
+	opts.walDir = value
 }
 
 // SetDeleteObsoleteFilesPeriodMicros sets the periodicity
@@ -675,6 +679,24 @@ func (opts *Options) SetWalSizeLimitMb(value uint64) {
 	C.rocksdb_options_set_WAL_size_limit_MB(opts.c, C.uint64_t(value))
 }
 
+// SetManualWALFlush enable/disable the manual flush of the WAL. If true,
+// the writes are kept in the WAL buffer of the db until DB.FlushWAL is
+// called, which reduces the overhead of small writes but the buffered writes
+// are lost if the process crashes.
+// Default: false
+func (opts *Options) SetManualWALFlush(value bool) {
+	C.rocksdb_options_set_manual_wal_flush(opts.c, boolToChar(value))
+}
+
+// SetAllowIngestBehind enable/disable ingesting the external files behind
+// all the existing data by IngestExternalFileOptions.SetIngestBehind, the
+// bottommost level is reserved for the ingested files.
+// It can not be changed after the database is created.
+// Default: false
+func (opts *Options) SetAllowIngestBehind(value bool) {
+	C.rocksdb_options_set_allow_ingest_behind(opts.c, boolToChar(value))
+}
+
 // SetManifestPreallocationSize sets the number of bytes
 // to preallocate (via fallocate) the manifest files.
 //
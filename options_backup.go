@@ -0,0 +1,131 @@
+package gorocksdb
+
+// #include <stdlib.h>
+// #include "rocksdb/c.h"
+import "C"
+import (
+	"time"
+	"unsafe"
+)
+
+// BackupableDBOptions represents the options used to open a backup engine
+// by OpenBackupEngineWithOptions.
+// share_files_with_checksum can not be set by the C API, the backup engine
+// uses the default of RocksDB for it.
+type BackupableDBOptions struct {
+	c         *C.rocksdb_backup_engine_options_t
+	backupDir string
+
+	progress     BackupProgressFunc
+	pollInterval time.Duration
+}
+
+// NewBackupableDBOptions creates a BackupableDBOptions object with the
+// directory where the backups are stored.
+func NewBackupableDBOptions(backupDir string) *BackupableDBOptions {
+	cDir := C.CString(backupDir)
+	defer C.free(unsafe.Pointer(cDir))
+	opts := NewNativeBackupableDBOptions(C.rocksdb_backup_engine_options_create(cDir))
+	opts.backupDir = backupDir
+	return opts
+}
+
+// NewNativeBackupableDBOptions creates a BackupableDBOptions object.
+func NewNativeBackupableDBOptions(c *C.rocksdb_backup_engine_options_t) *BackupableDBOptions {
+	return &BackupableDBOptions{c: c}
+}
+
+// SetBackupDir sets the directory where the backups are stored.
+func (opts *BackupableDBOptions) SetBackupDir(dir string) {
+	cDir := C.CString(dir)
+	defer C.free(unsafe.Pointer(cDir))
+	C.rocksdb_backup_engine_options_set_backup_dir(opts.c, cDir)
+	opts.backupDir = dir
+}
+
+// SetShareTableFiles specify if the table files are shared between the
+// backups. If false, each backup has its own copy of the table files.
+// Default: true
+func (opts *BackupableDBOptions) SetShareTableFiles(value bool) {
+	C.rocksdb_backup_engine_options_set_share_table_files(opts.c, boolToChar(value))
+}
+
+// SetSync specify if the backup files are synced to the storage. If false,
+// the backup may be corrupted on a machine crash, but not on a process crash.
+// Default: true
+func (opts *BackupableDBOptions) SetSync(value bool) {
+	C.rocksdb_backup_engine_options_set_sync(opts.c, boolToChar(value))
+}
+
+// SetDestroyOldData specify if all the existing backups are deleted
+// when the backup engine is opened.
+// Default: false
+func (opts *BackupableDBOptions) SetDestroyOldData(value bool) {
+	C.rocksdb_backup_engine_options_set_destroy_old_data(opts.c, boolToChar(value))
+}
+
+// SetBackupLogFiles specify if the log files are backed up. If false, the
+// memtable should be flushed before the backup to avoid losing the
+// unflushed data.
+// Default: true
+func (opts *BackupableDBOptions) SetBackupLogFiles(value bool) {
+	C.rocksdb_backup_engine_options_set_backup_log_files(opts.c, boolToChar(value))
+}
+
+// SetBackupRateLimit sets the max bytes per second of copying files
+// when taking backups. 0 means no limit.
+// Default: 0
+func (opts *BackupableDBOptions) SetBackupRateLimit(value uint64) {
+	C.rocksdb_backup_engine_options_set_backup_rate_limit(opts.c, C.uint64_t(value))
+}
+
+// SetRestoreRateLimit sets the max bytes per second of copying files
+// when restoring backups. 0 means no limit.
+// Default: 0
+func (opts *BackupableDBOptions) SetRestoreRateLimit(value uint64) {
+	C.rocksdb_backup_engine_options_set_restore_rate_limit(opts.c, C.uint64_t(value))
+}
+
+// SetMaxBackgroundOperations sets the number of threads used to copy
+// files during backups and restores.
+// Default: 1
+func (opts *BackupableDBOptions) SetMaxBackgroundOperations(value int) {
+	C.rocksdb_backup_engine_options_set_max_background_operations(opts.c, C.int(value))
+}
+
+// SetCallbackTriggerIntervalSize sets the least bytes copied between two
+// calls of the progress callback.
+// Default: 4194304 (4MB)
+func (opts *BackupableDBOptions) SetCallbackTriggerIntervalSize(value uint64) {
+	C.rocksdb_backup_engine_options_set_callback_trigger_interval_size(opts.c, C.uint64_t(value))
+}
+
+// BackupProgressFunc is called with the bytes copied so far while a backup
+// or a restore is running, see SetCallbackTriggerIntervalSize, and once more
+// with the total bytes when the backup or the restore ends.
+// The C API has no progress callback, so the bytes are got by polling the
+// total size of the files under the destination directories, which is only
+// an approximation: the temporary files being written and the meta files
+// are counted, as are the other changes of the directories meanwhile.
+type BackupProgressFunc func(copied uint64)
+
+// SetProgressCallback sets the callback invoked during the backups and the
+// restores of the backup engine opened with the options.
+func (opts *BackupableDBOptions) SetProgressCallback(fn BackupProgressFunc) {
+	opts.progress = fn
+}
+
+// SetProgressPollInterval sets the interval to poll the size of the
+// destination directories for the progress callback. Each poll walks all
+// the files under the directories, so the interval should be longer for
+// the directories with many files.
+// Default: 100ms
+func (opts *BackupableDBOptions) SetProgressPollInterval(value time.Duration) {
+	opts.pollInterval = value
+}
+
+// Destroy deallocates the BackupableDBOptions object.
+func (opts *BackupableDBOptions) Destroy() {
+	C.rocksdb_backup_engine_options_destroy(opts.c)
+	opts.c = nil
+}
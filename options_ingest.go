@@ -0,0 +1,67 @@
+package gorocksdb
+
+// #include "rocksdb/c.h"
+import "C"
+
+// IngestExternalFileOptions represent all of the available options when
+// ingesting external sst files into the database.
+type IngestExternalFileOptions struct {
+	c *C.rocksdb_ingestexternalfileoptions_t
+}
+
+// NewDefaultIngestExternalFileOptions creates a default IngestExternalFileOptions object.
+func NewDefaultIngestExternalFileOptions() *IngestExternalFileOptions {
+	return NewNativeIngestExternalFileOptions(C.rocksdb_ingestexternalfileoptions_create())
+}
+
+// NewNativeIngestExternalFileOptions creates a IngestExternalFileOptions object.
+func NewNativeIngestExternalFileOptions(c *C.rocksdb_ingestexternalfileoptions_t) *IngestExternalFileOptions {
+	return &IngestExternalFileOptions{c}
+}
+
+// SetMoveFiles specify if the files should be moved instead of copied.
+// Default: false
+func (opts *IngestExternalFileOptions) SetMoveFiles(value bool) {
+	C.rocksdb_ingestexternalfileoptions_set_move_files(opts.c, boolToChar(value))
+}
+
+// SetSnapshotConsistency specify if the ingested keys should be hidden from
+// the snapshots created before the ingestion. If false, an ingested key
+// could appear in the existing snapshots.
+// Default: true
+func (opts *IngestExternalFileOptions) SetSnapshotConsistency(value bool) {
+	C.rocksdb_ingestexternalfileoptions_set_snapshot_consistency(opts.c, boolToChar(value))
+}
+
+// SetAllowGlobalSeqNo specify if a global sequence number can be assigned
+// to the files. If false, the ingestion will fail if the key range of the
+// files overlaps with the existing keys or tombstones in the database.
+// Default: true
+func (opts *IngestExternalFileOptions) SetAllowGlobalSeqNo(value bool) {
+	C.rocksdb_ingestexternalfileoptions_set_allow_global_seqno(opts.c, boolToChar(value))
+}
+
+// SetAllowBlockingFlush specify if the memtable can be flushed during the
+// ingestion. If false and the key range of the files overlaps with the
+// memtable, the ingestion will fail.
+// Default: true
+func (opts *IngestExternalFileOptions) SetAllowBlockingFlush(value bool) {
+	C.rocksdb_ingestexternalfileoptions_set_allow_blocking_flush(opts.c, boolToChar(value))
+}
+
+// SetIngestBehind specify if the duplicate keys in the files should be
+// skipped rather than overwriting the existing data, which is useful to
+// back-fill the historical data. All the files are ingested at the
+// bottommost level with sequence number 0. It can only be used if the
+// database has been running with Options.SetAllowIngestBehind(true) since
+// it was created.
+// Default: false
+func (opts *IngestExternalFileOptions) SetIngestBehind(value bool) {
+	C.rocksdb_ingestexternalfileoptions_set_ingest_behind(opts.c, boolToChar(value))
+}
+
+// Destroy deallocates the IngestExternalFileOptions object.
+func (opts *IngestExternalFileOptions) Destroy() {
+	C.rocksdb_ingestexternalfileoptions_destroy(opts.c)
+	opts.c = nil
+}
@@ -0,0 +1,74 @@
+package gorocksdb
+
+// #include "rocksdb/c.h"
+import "C"
+
+// TransactionOptions represent all of the available options for a
+// transaction started by TransactionDB.TransactionBegin.
+type TransactionOptions struct {
+	c *C.rocksdb_transaction_options_t
+}
+
+// NewDefaultTransactionOptions creates a default TransactionOptions object.
+func NewDefaultTransactionOptions() *TransactionOptions {
+	return NewNativeTransactionOptions(C.rocksdb_transaction_options_create())
+}
+
+// NewNativeTransactionOptions creates a TransactionOptions object.
+func NewNativeTransactionOptions(c *C.rocksdb_transaction_options_t) *TransactionOptions {
+	return &TransactionOptions{c}
+}
+
+// SetSetSnapshot specify if a snapshot should be taken when the
+// transaction begins, which is the same as calling Transaction.SetSnapshot.
+// Default: false
+func (opts *TransactionOptions) SetSetSnapshot(value bool) {
+	C.rocksdb_transaction_options_set_set_snapshot(opts.c, boolToChar(value))
+}
+
+// SetDeadlockDetect specify if the transaction should check whether
+// acquiring a lock will cause a deadlock. If so, the operation returns
+// a Busy error and the transaction should be retried.
+// Default: false
+func (opts *TransactionOptions) SetDeadlockDetect(value bool) {
+	C.rocksdb_transaction_options_set_deadlock_detect(opts.c, boolToChar(value))
+}
+
+// SetDeadlockDetectDepth sets the number of traversals to make during
+// deadlock detection.
+// Default: 50
+func (opts *TransactionOptions) SetDeadlockDetectDepth(value int64) {
+	C.rocksdb_transaction_options_set_deadlock_detect_depth(opts.c, C.int64_t(value))
+}
+
+// SetLockTimeout sets the wait timeout in milliseconds when the transaction
+// attempts to lock a key.
+// If 0, no waiting is done if a lock cannot instantly be acquired.
+// If negative, TransactionDBOptions.SetTransactionLockTimeout will be used.
+// Default: -1
+func (opts *TransactionOptions) SetLockTimeout(value int64) {
+	C.rocksdb_transaction_options_set_lock_timeout(opts.c, C.int64_t(value))
+}
+
+// SetExpiration sets the expiration duration in milliseconds.
+// If non-negative, transactions that last longer than this many milliseconds
+// will fail to commit. If negative, a forgotten transaction that is never
+// committed, rolled back, or destroyed will never relinquish any locks it
+// holds.
+// Default: -1
+func (opts *TransactionOptions) SetExpiration(value int64) {
+	C.rocksdb_transaction_options_set_expiration(opts.c, C.int64_t(value))
+}
+
+// SetMaxWriteBatchSize sets the maximum number of bytes used for the write
+// batch of the transaction. 0 means no limit.
+// Default: 0
+func (opts *TransactionOptions) SetMaxWriteBatchSize(value uint64) {
+	C.rocksdb_transaction_options_set_max_write_batch_size(opts.c, C.size_t(value))
+}
+
+// Destroy deallocates the TransactionOptions object.
+func (opts *TransactionOptions) Destroy() {
+	C.rocksdb_transaction_options_destroy(opts.c)
+	opts.c = nil
+}
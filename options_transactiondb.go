@@ -0,0 +1,70 @@
+package gorocksdb
+
+// #include "rocksdb/c.h"
+import "C"
+
+// TransactionDBOptions represent all of the available options when opening a
+// transactional database with OpenTransactionDb.
+// The write policy can not be set by the C API, so the transactional
+// database always uses the default write-committed policy, the writes of a
+// transaction, even a prepared one, reach the memtable only at commit.
+type TransactionDBOptions struct {
+	c *C.rocksdb_transactiondb_options_t
+}
+
+// NewDefaultTransactionDBOptions creates a default TransactionDBOptions object.
+func NewDefaultTransactionDBOptions() *TransactionDBOptions {
+	return NewNativeTransactionDBOptions(C.rocksdb_transactiondb_options_create())
+}
+
+// NewNativeTransactionDBOptions creates a TransactionDBOptions object.
+func NewNativeTransactionDBOptions(c *C.rocksdb_transactiondb_options_t) *TransactionDBOptions {
+	return &TransactionDBOptions{c}
+}
+
+// SetMaxNumLocks sets the maximum number of keys that can be locked at the
+// same time per column family.
+// If the number of locked keys is greater than max_num_locks, transaction
+// writes (or GetForUpdate) will return an error.
+// If this value is not positive, no limit will be enforced.
+// Default: -1
+func (opts *TransactionDBOptions) SetMaxNumLocks(value int64) {
+	C.rocksdb_transactiondb_options_set_max_num_locks(opts.c, C.int64_t(value))
+}
+
+// SetNumStripes sets the concurrency level.
+// Increasing this value will increase the concurrency by dividing the lock
+// table (per column family) into more sub-tables, each with their own
+// separate mutex.
+// Default: 16
+func (opts *TransactionDBOptions) SetNumStripes(value uint64) {
+	C.rocksdb_transactiondb_options_set_num_stripes(opts.c, C.size_t(value))
+}
+
+// SetTransactionLockTimeout sets the default wait timeout in milliseconds
+// when a transaction attempts to lock a key and
+// TransactionOptions.SetLockTimeout is not specified.
+// If 0, no waiting is done if a lock cannot instantly be acquired.
+// If negative, there is no timeout, which is not recommended as it can lead
+// to deadlocks.
+// Default: 1000
+func (opts *TransactionDBOptions) SetTransactionLockTimeout(value int64) {
+	C.rocksdb_transactiondb_options_set_transaction_lock_timeout(opts.c, C.int64_t(value))
+}
+
+// SetDefaultLockTimeout sets the wait timeout in milliseconds when writing a
+// key OUTSIDE of a transaction (ie by calling TransactionDB.Put, Merge,
+// Delete or Write directly).
+// If 0, no waiting is done if a lock cannot instantly be acquired.
+// If negative, there is no timeout and will block indefinitely when acquiring
+// a lock.
+// Default: 1000
+func (opts *TransactionDBOptions) SetDefaultLockTimeout(value int64) {
+	C.rocksdb_transactiondb_options_set_default_lock_timeout(opts.c, C.int64_t(value))
+}
+
+// Destroy deallocates the TransactionDBOptions object.
+func (opts *TransactionDBOptions) Destroy() {
+	C.rocksdb_transactiondb_options_destroy(opts.c)
+	opts.c = nil
+}
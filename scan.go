@@ -0,0 +1,221 @@
+package gorocksdb
+
+import "bytes"
+
+// ScanOptions represents the range and the order of a scan by DB.Scan or
+// DB.NewRangeIterator. The bounds are compared bytewise, so the column
+// family should use the default comparator if Prefix is set.
+type ScanOptions struct {
+	// Start is the inclusive lower bound, nil means the first key.
+	Start []byte
+	// End is the exclusive upper bound, nil means after the last key.
+	End []byte
+	// Prefix limits the scan to the keys with the prefix, which is combined
+	// with Start and End if they are set too.
+	Prefix []byte
+	// Reverse scans from the upper bound to the lower bound.
+	Reverse bool
+	// Limit is the max number of the entries to scan, 0 means no limit.
+	Limit int
+	// Snapshot is the snapshot to read, nil means the current state.
+	Snapshot *Snapshot
+	// CF is the column family to read, nil means the default column family.
+	CF *ColumnFamilyHandle
+}
+
+// RangeIterator is a pull-style iterator over the range of ScanOptions.
+// It owns the read options and the bounds of the underlying Iterator,
+// which are freed by Close. Like Iterator, it should be protected by rlock
+// by caller.
+//
+// For example:
+//
+//	it, err := db.NewRangeIterator(ScanOptions{Prefix: []byte("foo")})
+//	if err != nil {
+//		return err
+//	}
+//	defer it.Close()
+//	for it.Next() {
+//		fmt.Printf("Key: %v Value: %v\n", it.Key(), it.Value())
+//	}
+//	return it.Err()
+type RangeIterator struct {
+	iter     *Iterator
+	ro       *ReadOptions
+	lower    *IterBound
+	upper    *IterBound
+	lowerKey []byte
+	upperKey []byte
+	reverse  bool
+	limit    int
+	count    int
+	started  bool
+	// err is set if the db is closed while reading the current entry.
+	err error
+}
+
+// NewRangeIterator creates a RangeIterator over the range of opts.
+func (db *DB) NewRangeIterator(opts ScanOptions) (*RangeIterator, error) {
+	lower, upper := scanBounds(opts)
+	it := &RangeIterator{
+		ro:       NewDefaultReadOptions(),
+		lowerKey: lower,
+		upperKey: upper,
+		reverse:  opts.Reverse,
+		limit:    opts.Limit,
+	}
+	if lower != nil {
+		it.lower = NewIterBound(lower)
+		it.ro.SetIterLowerBound(it.lower)
+	}
+	if upper != nil {
+		it.upper = NewIterBound(upper)
+		it.ro.SetIterUpperBound(it.upper)
+	}
+	if opts.Snapshot != nil {
+		it.ro.SetSnapshot(opts.Snapshot)
+	}
+	var err error
+	if opts.CF != nil {
+		it.iter, err = db.NewIteratorCF(it.ro, opts.CF)
+	} else {
+		it.iter, err = db.NewIterator(it.ro)
+	}
+	if err != nil {
+		it.Close()
+		return nil, err
+	}
+	return it, nil
+}
+
+// scanBounds returns the bounds of the scan, a nil bound is unbounded.
+func scanBounds(opts ScanOptions) (lower, upper []byte) {
+	lower, upper = opts.Start, opts.End
+	if opts.Prefix != nil {
+		if lower == nil || bytes.Compare(lower, opts.Prefix) < 0 {
+			lower = opts.Prefix
+		}
+		if succ := prefixSuccessor(opts.Prefix); succ != nil &&
+			(upper == nil || bytes.Compare(succ, upper) < 0) {
+			upper = succ
+		}
+	}
+	return lower, upper
+}
+
+// prefixSuccessor returns the smallest key which is greater than all the
+// keys with the prefix, or nil if there is no such key.
+func prefixSuccessor(prefix []byte) []byte {
+	for i := len(prefix) - 1; i >= 0; i-- {
+		if prefix[i] != 0xff {
+			succ := append([]byte(nil), prefix[:i+1]...)
+			succ[i]++
+			return succ
+		}
+	}
+	return nil
+}
+
+// Next moves to the next entry of the range, it returns false when the
+// range or the limit is reached, or an error happens.
+func (it *RangeIterator) Next() bool {
+	if it.limit > 0 && it.count >= it.limit {
+		return false
+	}
+	if !it.started {
+		it.started = true
+		switch {
+		case !it.reverse && it.lower != nil:
+			it.iter.Seek(it.lowerKey)
+		case !it.reverse:
+			it.iter.SeekToFirst()
+		case it.upper != nil:
+			it.iter.SeekForPrev(it.upperKey)
+		default:
+			it.iter.SeekToLast()
+		}
+	} else if it.reverse {
+		it.iter.Prev()
+	} else {
+		it.iter.Next()
+	}
+	if !it.iter.Valid() {
+		return false
+	}
+	it.count++
+	return true
+}
+
+// Key returns the key of the current entry, which is valid until the next
+// call to Next. It returns nil if the db is closed, see Err.
+func (it *RangeIterator) Key() []byte {
+	key := it.iter.Key()
+	if key == nil {
+		it.err = errDBClosed
+		return nil
+	}
+	return key.Data()
+}
+
+// Value returns the value of the current entry, which is valid until the
+// next call to Next. It returns nil if the db is closed, see Err.
+func (it *RangeIterator) Value() []byte {
+	value := it.iter.Value()
+	if value == nil {
+		it.err = errDBClosed
+		return nil
+	}
+	return value.Data()
+}
+
+// Err returns the error of the underlying Iterator, or errDBClosed if the
+// db is closed while reading the current entry.
+func (it *RangeIterator) Err() error {
+	if it.err != nil {
+		return it.err
+	}
+	return it.iter.Err()
+}
+
+// Close closes the iterator and frees the read options and the bounds, it
+// does nothing if the iterator is closed already.
+func (it *RangeIterator) Close() {
+	if it.iter != nil {
+		it.iter.Close()
+		it.iter = nil
+	}
+	if it.ro != nil {
+		it.ro.Destroy()
+		it.ro = nil
+	}
+	if it.lower != nil {
+		it.lower.Destroy()
+		it.lower = nil
+	}
+	if it.upper != nil {
+		it.upper.Destroy()
+		it.upper = nil
+	}
+}
+
+// Scan calls fn for the entries in the range of opts in order, the key and
+// the value are only valid during the call. The scan stops at the first
+// error returned by fn or the iterator, which is returned. Like NewIterator,
+// it should be protected by rlock by caller.
+func (db *DB) Scan(opts ScanOptions, fn func(key, value []byte) error) error {
+	it, err := db.NewRangeIterator(opts)
+	if err != nil {
+		return err
+	}
+	defer it.Close()
+	for it.Next() {
+		key, value := it.Key(), it.Value()
+		if it.err != nil {
+			break
+		}
+		if err := fn(key, value); err != nil {
+			return err
+		}
+	}
+	return it.Err()
+}
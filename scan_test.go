@@ -0,0 +1,98 @@
+package gorocksdb
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/facebookgo/ensure"
+)
+
+func TestDBScan(t *testing.T) {
+	db := newTestDB(t, "TestDBScan", nil)
+	defer db.Close()
+
+	wo := NewDefaultWriteOptions()
+	for _, k := range []string{"a1", "b1", "b2", "b3", "c1"} {
+		ensure.Nil(t, db.Put(wo, []byte(k), []byte("v"+k)))
+	}
+
+	scan := func(opts ScanOptions) []string {
+		var keys []string
+		ensure.Nil(t, db.Scan(opts, func(key, value []byte) error {
+			ensure.DeepEqual(t, string(value), "v"+string(key))
+			keys = append(keys, string(key))
+			return nil
+		}))
+		return keys
+	}
+	ensure.DeepEqual(t, scan(ScanOptions{}), []string{"a1", "b1", "b2", "b3", "c1"})
+	ensure.DeepEqual(t, scan(ScanOptions{Prefix: []byte("b")}), []string{"b1", "b2", "b3"})
+	ensure.DeepEqual(t, scan(ScanOptions{Prefix: []byte("b"), Reverse: true}), []string{"b3", "b2", "b1"})
+	ensure.DeepEqual(t, scan(ScanOptions{Start: []byte("b2"), End: []byte("c1")}), []string{"b2", "b3"})
+	ensure.DeepEqual(t, scan(ScanOptions{End: []byte("b2"), Reverse: true}), []string{"b1", "a1"})
+	ensure.DeepEqual(t, scan(ScanOptions{Reverse: true, Limit: 2}), []string{"c1", "b3"})
+	ensure.DeepEqual(t, scan(ScanOptions{Prefix: []byte("b"), Start: []byte("b2"), Limit: 1}), []string{"b2"})
+
+	errStop := errors.New("stop")
+	err := db.Scan(ScanOptions{}, func(key, value []byte) error {
+		return errStop
+	})
+	ensure.DeepEqual(t, err, errStop)
+}
+
+func TestDBScanSnapshot(t *testing.T) {
+	db := newTestDB(t, "TestDBScanSnapshot", nil)
+	defer db.Close()
+
+	wo := NewDefaultWriteOptions()
+	ensure.Nil(t, db.Put(wo, []byte("key1"), []byte("val1")))
+	snap, err := db.NewSnapshot()
+	ensure.Nil(t, err)
+	defer snap.Release()
+	ensure.Nil(t, db.Put(wo, []byte("key2"), []byte("val2")))
+
+	it, err := db.NewRangeIterator(ScanOptions{Snapshot: snap})
+	ensure.Nil(t, err)
+	defer it.Close()
+	ensure.True(t, it.Next())
+	ensure.DeepEqual(t, it.Key(), []byte("key1"))
+	ensure.False(t, it.Next())
+	ensure.Nil(t, it.Err())
+}
+
+func TestRangeIteratorCloseTwice(t *testing.T) {
+	db := newTestDB(t, "TestRangeIteratorCloseTwice", nil)
+	defer db.Close()
+
+	wo := NewDefaultWriteOptions()
+	ensure.Nil(t, db.Put(wo, []byte("key1"), []byte("val1")))
+	it, err := db.NewRangeIterator(ScanOptions{Start: []byte("key0"), End: []byte("key2")})
+	ensure.Nil(t, err)
+	ensure.True(t, it.Next())
+	it.Close()
+	// the bounds and the read options are not freed twice.
+	it.Close()
+}
+
+func TestRangeIteratorDBClosed(t *testing.T) {
+	db := newTestDB(t, "TestRangeIteratorDBClosed", nil)
+
+	wo := NewDefaultWriteOptions()
+	ensure.Nil(t, db.Put(wo, []byte("key1"), []byte("val1")))
+	it, err := db.NewRangeIterator(ScanOptions{})
+	ensure.Nil(t, err)
+	defer it.Close()
+	ensure.True(t, it.Next())
+
+	db.Close()
+	ensure.True(t, it.Key() == nil)
+	ensure.True(t, it.Value() == nil)
+	ensure.DeepEqual(t, it.Err(), errDBClosed)
+	ensure.False(t, it.Next())
+}
+
+func TestPrefixSuccessor(t *testing.T) {
+	ensure.DeepEqual(t, prefixSuccessor([]byte("ab")), []byte("ac"))
+	ensure.DeepEqual(t, prefixSuccessor([]byte{'a', 0xff}), []byte("b"))
+	ensure.True(t, prefixSuccessor([]byte{0xff, 0xff}) == nil)
+}
@@ -1,8 +1,12 @@
 package gorocksdb
 
 // #include <stdlib.h>
+// #include "rocksdb/c.h"
 import "C"
-import "unsafe"
+import (
+	"sync/atomic"
+	"unsafe"
+)
 
 // Slice is used as a wrapper for non-copy values
 type Slice struct {
@@ -38,3 +42,73 @@ func (s *Slice) Free() {
 		s.freed = true
 	}
 }
+
+// PinnableSlice is a value which references the memory of the db, like the
+// block cache, rather than a copy. It should be destroyed after use. The
+// slices read from a db are released when the db is closed, and their
+// Data returns nil after that, the data returned before is invalid then.
+type PinnableSlice struct {
+	c *C.rocksdb_pinnableslice_t
+	// db is the db the slice is read from, the slice is released when the
+	// db is closed. It's nil if the slice is not on a db.
+	db *DB
+	// released is set once the slice is released by closing the db.
+	released int32
+}
+
+// NewNativePinnableSlice returns a PinnableSlice with the c pinnable slice.
+func NewNativePinnableSlice(c *C.rocksdb_pinnableslice_t) *PinnableSlice {
+	return &PinnableSlice{c: c}
+}
+
+// newDBPinnableSlice returns a PinnableSlice read from the db, the caller
+// should hold the db read lock. The slice of a key not found is not tracked
+// since it has nothing to release.
+func newDBPinnableSlice(db *DB, c *C.rocksdb_pinnableslice_t) (*PinnableSlice, error) {
+	s := &PinnableSlice{c: c}
+	if c == nil {
+		return s, nil
+	}
+	s.db = db
+	if !db.track(s) {
+		C.rocksdb_pinnableslice_destroy(c)
+		return nil, errDBClosed
+	}
+	return s, nil
+}
+
+func (s *PinnableSlice) release() {
+	atomic.StoreInt32(&s.released, 1)
+	C.rocksdb_pinnableslice_destroy(s.c)
+}
+
+// Data returns the data of the slice, which is only valid before Destroy.
+// It returns nil if the key is not found or the slice is released by closing
+// the db.
+func (s *PinnableSlice) Data() []byte {
+	if s.c == nil || atomic.LoadInt32(&s.released) != 0 {
+		return nil
+	}
+	var cValLen C.size_t
+	cValue := C.rocksdb_pinnableslice_value(s.c, &cValLen)
+	return charToByte(cValue, cValLen)
+}
+
+// Exists returns true if the key is found.
+func (s *PinnableSlice) Exists() bool {
+	return s.c != nil
+}
+
+// Destroy releases the pinned memory, it does nothing if the slice is
+// released by closing the db.
+func (s *PinnableSlice) Destroy() {
+	if s.c == nil {
+		return
+	}
+	if s.db != nil {
+		s.db.untrack(s)
+	} else {
+		C.rocksdb_pinnableslice_destroy(s.c)
+	}
+	s.c = nil
+}
@@ -2,11 +2,21 @@ package gorocksdb
 
 // #include "rocksdb/c.h"
 import "C"
+import "unsafe"
 
 // Snapshot provides a consistent view of read operations in a DB.
 type Snapshot struct {
 	c   *C.rocksdb_snapshot_t
 	cDb *C.rocksdb_t
+	// db is the db the snapshot is created from, the snapshot is released
+	// when the db is closed.
+	db *DB
+	// txnDB is the transaction db the snapshot is created from, which
+	// releases the snapshot.
+	txnDB *TransactionDB
+	// handleOnly is true if the snapshot is owned by a transaction, only
+	// the handle is freed by Release.
+	handleOnly bool
 }
 
 func NewSnapshot(db *DB) (*Snapshot, error) {
@@ -15,11 +25,32 @@ func NewSnapshot(db *DB) (*Snapshot, error) {
 
 // NewNativeSnapshot creates a Snapshot object.
 func NewNativeSnapshot(c *C.rocksdb_snapshot_t, cDb *C.rocksdb_t) *Snapshot {
-	return &Snapshot{c, cDb}
+	return &Snapshot{c: c, cDb: cDb}
 }
 
-// Release removes the snapshot from the database's list of snapshots.
-func (s *Snapshot) Release() {
+func (s *Snapshot) release() {
 	C.rocksdb_release_snapshot(s.cDb, s.c)
 	s.c, s.cDb = nil, nil
 }
+
+// Release removes the snapshot from the database's list of snapshots.
+func (s *Snapshot) Release() {
+	if s.handleOnly {
+		if s.c != nil {
+			C.rocksdb_free(unsafe.Pointer(s.c))
+			s.c = nil
+		}
+		return
+	}
+	if s.txnDB != nil {
+		s.txnDB.ReleaseSnapshot(s)
+		return
+	}
+	if s.db == nil {
+		C.rocksdb_release_snapshot(s.cDb, s.c)
+		s.c, s.cDb = nil, nil
+		return
+	}
+	// it does nothing if the snapshot is released by closing the db.
+	s.db.untrack(s)
+}
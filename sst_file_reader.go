@@ -0,0 +1,120 @@
+package gorocksdb
+
+// #include "rocksdb/c.h"
+import "C"
+import (
+	"errors"
+	"io/ioutil"
+	"os"
+)
+
+var errSstFileNotOpened = errors.New("no sst file opened")
+
+// SstFileReader is used to read a sst file outside of a database, such as
+// the files created by SstFileWriter before they are ingested.
+// The C API has no table reader, so the file is copied into a temporary
+// database by ingesting it, whose iterators read it. Only the files which
+// can be ingested can be read, e.g. the files of a database can't since
+// they have no external file version.
+type SstFileReader struct {
+	opts *Options
+	path string
+	// dir is the dir of the temporary database opened with dbOpts.
+	dir    string
+	dbOpts *Options
+	db     *DB
+}
+
+// NewSstFileReader creates a SstFileReader object. The options should be
+// the same as the file is written with, the comparator and table format are
+// taken from it.
+func NewSstFileReader(opts *Options) *SstFileReader {
+	return &SstFileReader{opts: opts}
+}
+
+// Open opens the sst file located at path for reading, the file opened
+// before is closed.
+func (r *SstFileReader) Open(path string) error {
+	r.close()
+	dir, err := ioutil.TempDir("", "gorocksdb-sstfilereader")
+	if err != nil {
+		return err
+	}
+	// the copy holds the same comparator and table options, which are
+	// owned by opts.
+	dbOpts := &Options{
+		c:    C.rocksdb_options_create_copy(r.opts.c),
+		env:  r.opts.env,
+		bbto: r.opts.bbto,
+	}
+	dbOpts.SetCreateIfMissing(true)
+	dbOpts.SetWalDir("")
+	db, err := OpenDb(dbOpts, dir)
+	if err != nil {
+		dbOpts.Destroy()
+		os.RemoveAll(dir)
+		return err
+	}
+	ingestOpts := NewDefaultIngestExternalFileOptions()
+	defer ingestOpts.Destroy()
+	if err := db.IngestExternalFile([]string{path}, ingestOpts); err != nil {
+		db.Close()
+		dbOpts.Destroy()
+		os.RemoveAll(dir)
+		return err
+	}
+	r.path, r.dir, r.dbOpts, r.db = path, dir, dbOpts, db
+	return nil
+}
+
+// NewIterator returns an Iterator over the opened file that uses the
+// ReadOptions given. The iterator is released when the reader is destroyed
+// or opens another file.
+func (r *SstFileReader) NewIterator(opts *ReadOptions) (*Iterator, error) {
+	if r.db == nil {
+		return nil, errSstFileNotOpened
+	}
+	return r.db.NewIterator(opts)
+}
+
+// VerifyChecksum reads all the blocks of the opened file and verifies their
+// checksums.
+func (r *SstFileReader) VerifyChecksum() error {
+	ro := NewDefaultReadOptions()
+	defer ro.Destroy()
+	ro.SetVerifyChecksums(true)
+	ro.SetFillCache(false)
+	iter, err := r.NewIterator(ro)
+	if err != nil {
+		return err
+	}
+	defer iter.Close()
+	for iter.SeekToFirst(); iter.Valid(); iter.Next() {
+	}
+	return iter.Err()
+}
+
+// GetTableProperties returns the table properties of the opened file, which
+// are read from the properties block of the file in Go. The block based
+// table files of format version 0 to 5 are supported.
+func (r *SstFileReader) GetTableProperties() (*TableProperties, error) {
+	if r.db == nil {
+		return nil, errSstFileNotOpened
+	}
+	return readTableProperties(r.path)
+}
+
+func (r *SstFileReader) close() {
+	if r.db == nil {
+		return
+	}
+	r.db.Close()
+	r.dbOpts.Destroy()
+	os.RemoveAll(r.dir)
+	r.path, r.dir, r.dbOpts, r.db = "", "", nil, nil
+}
+
+// Destroy closes the opened file and removes its temporary database.
+func (r *SstFileReader) Destroy() {
+	r.close()
+}
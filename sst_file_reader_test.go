@@ -0,0 +1,69 @@
+package gorocksdb
+
+import (
+	"io/ioutil"
+	"os"
+	"testing"
+
+	"github.com/facebookgo/ensure"
+)
+
+func TestSstFileReader(t *testing.T) {
+	f, err := ioutil.TempFile("", "gorocksdb-TestSstFileReader")
+	ensure.Nil(t, err)
+	f.Close()
+	defer os.Remove(f.Name())
+
+	givenKeys := [][]byte{[]byte("key1"), []byte("key2"), []byte("key3")}
+	envOpts := NewDefaultEnvOptions()
+	defer envOpts.Destroy()
+	opts := NewDefaultOptions()
+	w := NewSstFileWriter(envOpts, opts)
+	defer w.Destroy()
+	ensure.Nil(t, w.Open(f.Name()))
+	for _, k := range givenKeys {
+		ensure.Nil(t, w.Put(k, []byte("val")))
+	}
+	ensure.Nil(t, w.Finish())
+
+	r := NewSstFileReader(opts)
+	defer r.Destroy()
+	_, err = r.GetTableProperties()
+	ensure.DeepEqual(t, err, errSstFileNotOpened)
+	ensure.Nil(t, r.Open(f.Name()))
+	ensure.Nil(t, r.VerifyChecksum())
+
+	props, err := r.GetTableProperties()
+	ensure.Nil(t, err)
+	ensure.DeepEqual(t, props.NumEntries, uint64(len(givenKeys)))
+	ensure.DeepEqual(t, props.NumDataBlocks, uint64(1))
+	ensure.True(t, props.DataSize > 0)
+	ensure.True(t, props.IndexSize > 0)
+	ensure.DeepEqual(t, props.RawValueSize, uint64(3*len("val")))
+	ensure.DeepEqual(t, props.ComparatorName, "leveldb.BytewiseComparator")
+	ensure.True(t, props.CompressionName != "")
+
+	ro := NewDefaultReadOptions()
+	iter, err := r.NewIterator(ro)
+	ensure.Nil(t, err)
+	defer iter.Close()
+	var actualKeys [][]byte
+	for iter.SeekToFirst(); iter.Valid(); iter.Next() {
+		actualKeys = append(actualKeys, iter.Key().Bytes())
+	}
+	ensure.Nil(t, iter.Err())
+	ensure.DeepEqual(t, actualKeys, givenKeys)
+
+	// a file which is not a table can't be opened.
+	g, err := ioutil.TempFile("", "gorocksdb-TestSstFileReader")
+	ensure.Nil(t, err)
+	defer os.Remove(g.Name())
+	_, err = g.Write(make([]byte, 100))
+	ensure.Nil(t, err)
+	g.Close()
+	_, err = readTableProperties(g.Name())
+	ensure.DeepEqual(t, err, errNotBlockBasedTable)
+	ensure.NotNil(t, r.Open(g.Name()))
+	_, err = r.NewIterator(ro)
+	ensure.DeepEqual(t, err, errSstFileNotOpened)
+}
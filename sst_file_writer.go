@@ -0,0 +1,127 @@
+package gorocksdb
+
+// #include <stdlib.h>
+// #include "rocksdb/c.h"
+import "C"
+import (
+	"errors"
+	"unsafe"
+)
+
+// SstFileWriter is used to create sst files that can be ingested into the
+// database later by DB.IngestExternalFile.
+// All keys in files generated by SstFileWriter will have sequence number = 0.
+type SstFileWriter struct {
+	c *C.rocksdb_sstfilewriter_t
+}
+
+// NewSstFileWriter creates a SstFileWriter object. The options should be the
+// same as the database or column family the files will be ingested into,
+// the comparator and table format are taken from it.
+func NewSstFileWriter(envOpts *EnvOptions, opts *Options) *SstFileWriter {
+	return &SstFileWriter{C.rocksdb_sstfilewriter_create(envOpts.c, opts.c)}
+}
+
+// Open prepares the SstFileWriter to write into the file located at path.
+func (w *SstFileWriter) Open(path string) error {
+	var (
+		cErr  *C.char
+		cPath = C.CString(path)
+	)
+	defer C.free(unsafe.Pointer(cPath))
+	C.rocksdb_sstfilewriter_open(w.c, cPath, &cErr)
+	if cErr != nil {
+		defer C.free(unsafe.Pointer(cErr))
+		return errors.New(C.GoString(cErr))
+	}
+	return nil
+}
+
+// Put adds a key-value pair to the opened file.
+// REQUIRES: key is after any previously added key according to comparator.
+func (w *SstFileWriter) Put(key, value []byte) error {
+	var (
+		cErr   *C.char
+		cKey   = byteToChar(key)
+		cValue = byteToChar(value)
+	)
+	C.rocksdb_sstfilewriter_put(w.c, cKey, C.size_t(len(key)), cValue, C.size_t(len(value)), &cErr)
+	if cErr != nil {
+		defer C.free(unsafe.Pointer(cErr))
+		return errors.New(C.GoString(cErr))
+	}
+	return nil
+}
+
+// Merge adds a merge of "value" with the existing value of "key" to the
+// opened file.
+// REQUIRES: key is after any previously added key according to comparator.
+func (w *SstFileWriter) Merge(key, value []byte) error {
+	var (
+		cErr   *C.char
+		cKey   = byteToChar(key)
+		cValue = byteToChar(value)
+	)
+	C.rocksdb_sstfilewriter_merge(w.c, cKey, C.size_t(len(key)), cValue, C.size_t(len(value)), &cErr)
+	if cErr != nil {
+		defer C.free(unsafe.Pointer(cErr))
+		return errors.New(C.GoString(cErr))
+	}
+	return nil
+}
+
+// Delete adds a deletion of the key to the opened file.
+// REQUIRES: key is after any previously added key according to comparator.
+func (w *SstFileWriter) Delete(key []byte) error {
+	var (
+		cErr *C.char
+		cKey = byteToChar(key)
+	)
+	C.rocksdb_sstfilewriter_delete(w.c, cKey, C.size_t(len(key)), &cErr)
+	if cErr != nil {
+		defer C.free(unsafe.Pointer(cErr))
+		return errors.New(C.GoString(cErr))
+	}
+	return nil
+}
+
+// DeleteRange adds a deletion of the keys in the range [start, end) to the
+// opened file. The range deletions need not be ordered with the other keys.
+func (w *SstFileWriter) DeleteRange(start, end []byte) error {
+	var (
+		cErr   *C.char
+		cStart = byteToChar(start)
+		cEnd   = byteToChar(end)
+	)
+	C.rocksdb_sstfilewriter_delete_range(w.c, cStart, C.size_t(len(start)), cEnd, C.size_t(len(end)), &cErr)
+	if cErr != nil {
+		defer C.free(unsafe.Pointer(cErr))
+		return errors.New(C.GoString(cErr))
+	}
+	return nil
+}
+
+// FileSize returns the current size of the file being written.
+func (w *SstFileWriter) FileSize() uint64 {
+	var cSize C.uint64_t
+	C.rocksdb_sstfilewriter_file_size(w.c, &cSize)
+	return uint64(cSize)
+}
+
+// Finish finishes writing to the sst file and closes it. The writer can be
+// opened again for a new file.
+func (w *SstFileWriter) Finish() error {
+	var cErr *C.char
+	C.rocksdb_sstfilewriter_finish(w.c, &cErr)
+	if cErr != nil {
+		defer C.free(unsafe.Pointer(cErr))
+		return errors.New(C.GoString(cErr))
+	}
+	return nil
+}
+
+// Destroy deallocates the SstFileWriter object.
+func (w *SstFileWriter) Destroy() {
+	C.rocksdb_sstfilewriter_destroy(w.c)
+	w.c = nil
+}
@@ -0,0 +1,56 @@
+package gorocksdb
+
+import (
+	"io/ioutil"
+	"os"
+	"testing"
+
+	"github.com/facebookgo/ensure"
+)
+
+func TestSstFileWriterIngest(t *testing.T) {
+	db := newTestDB(t, "TestSstFileWriterIngest", nil)
+	defer db.Close()
+
+	var (
+		givenKeys = [][]byte{[]byte("key1"), []byte("key2"), []byte("key3")}
+		givenVal  = []byte("val")
+		wo        = NewDefaultWriteOptions()
+		ro        = NewDefaultReadOptions()
+	)
+	ensure.Nil(t, db.Put(wo, []byte("key0"), givenVal))
+	ensure.Nil(t, db.Put(wo, givenKeys[1], []byte("old")))
+
+	f, err := ioutil.TempFile("", "gorocksdb-TestSstFileWriterIngest")
+	ensure.Nil(t, err)
+	f.Close()
+	defer os.Remove(f.Name())
+
+	envOpts := NewDefaultEnvOptions()
+	defer envOpts.Destroy()
+	opts := NewDefaultOptions()
+	w := NewSstFileWriter(envOpts, opts)
+	defer w.Destroy()
+	ensure.Nil(t, w.Open(f.Name()))
+	for _, k := range givenKeys {
+		ensure.Nil(t, w.Put(k, givenVal))
+	}
+	ensure.Nil(t, w.DeleteRange([]byte("key0"), []byte("key1")))
+	// keys must be added in order
+	ensure.NotNil(t, w.Put([]byte("key0"), givenVal))
+	ensure.True(t, w.FileSize() > 0)
+	ensure.Nil(t, w.Finish())
+
+	ingestOpts := NewDefaultIngestExternalFileOptions()
+	defer ingestOpts.Destroy()
+	ensure.Nil(t, db.IngestExternalFile([]string{f.Name()}, ingestOpts))
+
+	for _, k := range givenKeys {
+		v, err := db.GetBytes(ro, k)
+		ensure.Nil(t, err)
+		ensure.DeepEqual(t, v, givenVal)
+	}
+	v, err := db.GetBytes(ro, []byte("key0"))
+	ensure.Nil(t, err)
+	ensure.True(t, v == nil)
+}
@@ -0,0 +1,269 @@
+package gorocksdb
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"os"
+)
+
+// The physical format of the block based table files, see
+// table/format.h. A file ends with a footer of:
+//
+//	checksum type (1 byte), metaindex handle, index handle,
+//	padding to 2*20 bytes, format version (4 bytes), magic (8 bytes)
+//
+// The legacy footer of format version 0 has no checksum type and no format
+// version. A handle is the offset and the size of a block as varints, each
+// block is followed by a trailer of the compression type (1 byte) and the
+// checksum (4 bytes). The metaindex block maps the names of the meta blocks,
+// like the properties block, to their handles.
+const (
+	tableMagic       = 0x88e241b785f4cff7
+	tableLegacyMagic = 0xdb4775248b80fb57
+
+	tableFooterSize       = 1 + 2*20 + 4 + 8
+	tableLegacyFooterSize = 2*20 + 8
+	tableBlockTrailerSize = 5
+	// format version 6 replaces the handles in the footer, which is not
+	// supported.
+	tableMaxFormatVersion = 5
+
+	tableNoCompression  = 0
+	tableChecksumCRC32c = 1
+
+	tablePropertiesBlock    = "rocksdb.properties"
+	tablePropertiesBlockOld = "rocksdb.stats"
+)
+
+var (
+	errNotBlockBasedTable = errors.New("not a block based table file")
+	errTableCorrupted     = errors.New("corrupted table block")
+)
+
+// TableProperties contains the properties of a sst file.
+type TableProperties struct {
+	NumEntries        uint64
+	NumDeletions      uint64
+	NumMergeOperands  uint64
+	NumRangeDeletions uint64
+	NumDataBlocks     uint64
+	DataSize          uint64
+	IndexSize         uint64
+	FilterSize        uint64
+	RawKeySize        uint64
+	RawValueSize      uint64
+	ComparatorName    string
+	CompressionName   string
+	// CreationTime is the unix time in seconds when the file is created,
+	// 0 if unknown.
+	CreationTime uint64
+	// FormatVersion is the format version of the block based table.
+	FormatVersion uint32
+}
+
+// blockHandle is the location of a block in the table file.
+type blockHandle struct {
+	offset uint64
+	size   uint64
+}
+
+func decodeBlockHandle(data []byte) (blockHandle, int, error) {
+	offset, n := binary.Uvarint(data)
+	if n <= 0 {
+		return blockHandle{}, 0, errTableCorrupted
+	}
+	size, m := binary.Uvarint(data[n:])
+	if m <= 0 {
+		return blockHandle{}, 0, errTableCorrupted
+	}
+	return blockHandle{offset, size}, n + m, nil
+}
+
+// tableFooter is the footer of a block based table file.
+type tableFooter struct {
+	checksumType  byte
+	metaindex     blockHandle
+	formatVersion uint32
+}
+
+func readTableFooter(f *os.File) (*tableFooter, error) {
+	info, err := f.Stat()
+	if err != nil {
+		return nil, err
+	}
+	size := info.Size()
+	if size < tableLegacyFooterSize {
+		return nil, errNotBlockBasedTable
+	}
+	n := int64(tableFooterSize)
+	if size < n {
+		n = tableLegacyFooterSize
+	}
+	buf := make([]byte, n)
+	if _, err := f.ReadAt(buf, size-n); err != nil {
+		return nil, err
+	}
+
+	var footer tableFooter
+	switch binary.LittleEndian.Uint64(buf[n-8:]) {
+	case tableLegacyMagic:
+		buf = buf[n-tableLegacyFooterSize:]
+		footer.checksumType = tableChecksumCRC32c
+	case tableMagic:
+		if n != tableFooterSize {
+			return nil, errNotBlockBasedTable
+		}
+		footer.checksumType = buf[0]
+		footer.formatVersion = binary.LittleEndian.Uint32(buf[n-12:])
+		if footer.formatVersion > tableMaxFormatVersion {
+			return nil, fmt.Errorf("unsupported table format version %d", footer.formatVersion)
+		}
+		buf = buf[1:]
+	default:
+		return nil, errNotBlockBasedTable
+	}
+	footer.metaindex, _, err = decodeBlockHandle(buf)
+	if err != nil {
+		return nil, err
+	}
+	return &footer, nil
+}
+
+// readTableBlock reads the uncompressed block of the handle and checks its
+// checksum if it's a crc32c one, the other checksum types are not checked.
+func readTableBlock(f *os.File, footer *tableFooter, h blockHandle) ([]byte, error) {
+	buf := make([]byte, h.size+tableBlockTrailerSize)
+	if _, err := f.ReadAt(buf, int64(h.offset)); err != nil {
+		if err == io.EOF {
+			return nil, errTableCorrupted
+		}
+		return nil, err
+	}
+	trailer := buf[h.size:]
+	if trailer[0] != tableNoCompression {
+		return nil, errTableCorrupted
+	}
+	if footer.checksumType == tableChecksumCRC32c {
+		expected := unmaskCRC(binary.LittleEndian.Uint32(trailer[1:]))
+		if crc32.Checksum(buf[:h.size+1], crc32cTable) != expected {
+			return nil, errTableCorrupted
+		}
+	}
+	return buf[:h.size], nil
+}
+
+// iterateBlock calls fn for the entries of the block in order. An entry is
+// the shared length of the key with the previous one, the non shared
+// length, the value length as varints, the non shared key and the value,
+// the restart points are at the end of the block.
+func iterateBlock(block []byte, fn func(key, value []byte) error) error {
+	if len(block) < 4 {
+		return errTableCorrupted
+	}
+	numRestarts := uint64(binary.LittleEndian.Uint32(block[len(block)-4:]))
+	if (numRestarts+1)*4 > uint64(len(block)) {
+		return errTableCorrupted
+	}
+	data := block[:uint64(len(block))-(numRestarts+1)*4]
+	var key []byte
+	for len(data) > 0 {
+		var lens [3]uint64
+		for i := range lens {
+			v, n := binary.Uvarint(data)
+			if n <= 0 {
+				return errTableCorrupted
+			}
+			lens[i], data = v, data[n:]
+		}
+		shared, nonShared, valueLen := lens[0], lens[1], lens[2]
+		if shared > uint64(len(key)) || nonShared+valueLen > uint64(len(data)) {
+			return errTableCorrupted
+		}
+		key = append(key[:shared], data[:nonShared]...)
+		if err := fn(key, data[nonShared:nonShared+valueLen]); err != nil {
+			return err
+		}
+		data = data[nonShared+valueLen:]
+	}
+	return nil
+}
+
+// readTableProperties reads the properties block of the block based table
+// file at path.
+func readTableProperties(path string) (*TableProperties, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	footer, err := readTableFooter(f)
+	if err != nil {
+		return nil, err
+	}
+	metaindex, err := readTableBlock(f, footer, footer.metaindex)
+	if err != nil {
+		return nil, err
+	}
+	var (
+		handle blockHandle
+		found  bool
+	)
+	err = iterateBlock(metaindex, func(key, value []byte) error {
+		if name := string(key); name != tablePropertiesBlock && name != tablePropertiesBlockOld {
+			return nil
+		}
+		var err error
+		handle, _, err = decodeBlockHandle(value)
+		found = true
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	if !found {
+		return nil, errors.New("no properties block in the table file")
+	}
+	block, err := readTableBlock(f, footer, handle)
+	if err != nil {
+		return nil, err
+	}
+
+	props := &TableProperties{FormatVersion: footer.formatVersion}
+	uints := map[string]*uint64{
+		"rocksdb.num.entries":         &props.NumEntries,
+		"rocksdb.deleted.keys":        &props.NumDeletions,
+		"rocksdb.merge.operands":      &props.NumMergeOperands,
+		"rocksdb.num.range-deletions": &props.NumRangeDeletions,
+		"rocksdb.num.data.blocks":     &props.NumDataBlocks,
+		"rocksdb.data.size":           &props.DataSize,
+		"rocksdb.index.size":          &props.IndexSize,
+		"rocksdb.filter.size":         &props.FilterSize,
+		"rocksdb.raw.key.size":        &props.RawKeySize,
+		"rocksdb.raw.value.size":      &props.RawValueSize,
+		"rocksdb.creation.time":       &props.CreationTime,
+	}
+	strs := map[string]*string{
+		"rocksdb.comparator":  &props.ComparatorName,
+		"rocksdb.compression": &props.CompressionName,
+	}
+	err = iterateBlock(block, func(key, value []byte) error {
+		if p, ok := uints[string(key)]; ok {
+			v, n := binary.Uvarint(value)
+			if n <= 0 {
+				return errTableCorrupted
+			}
+			*p = v
+		} else if p, ok := strs[string(key)]; ok {
+			*p = string(value)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return props, nil
+}
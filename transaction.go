@@ -0,0 +1,414 @@
+package gorocksdb
+
+// #include <stdlib.h>
+// #include "rocksdb/c.h"
+import "C"
+import (
+	"errors"
+	"strings"
+	"unsafe"
+)
+
+// The prefixes of the rocksdb status message for Busy and TryAgain.
+const (
+	statusBusyPrefix     = "Resource busy"
+	statusTryAgainPrefix = "Operation failed. Try again."
+)
+
+// ConflictError is returned by a transaction operation if it conflicts with
+// another write, which is a Busy or TryAgain status in rocksdb. The
+// transaction should be rolled back and can be retried.
+type ConflictError struct {
+	// TryAgain is true for a TryAgain status, false for a Busy status.
+	TryAgain bool
+	msg      string
+}
+
+func (e *ConflictError) Error() string {
+	return e.msg
+}
+
+// IsConflict returns true if the error is a *ConflictError.
+func IsConflict(err error) bool {
+	_, ok := err.(*ConflictError)
+	return ok
+}
+
+// newTxnError converts the error message returned from a transaction
+// operation to an error, the conflict errors are typed as *ConflictError.
+func newTxnError(msg string) error {
+	if strings.HasPrefix(msg, statusBusyPrefix) {
+		return &ConflictError{msg: msg}
+	}
+	if strings.HasPrefix(msg, statusTryAgainPrefix) {
+		return &ConflictError{TryAgain: true, msg: msg}
+	}
+	return errors.New(msg)
+}
+
+// txnDB is the database a Transaction is begun from. The transaction holds
+// the read lock of the database during each operation, so Close will not
+// race with an in-flight commit.
+type txnDB interface {
+	RLock()
+	RUnlock()
+	IsOpened() bool
+}
+
+// Transaction is used with TransactionDB or OptimisticTransactionDB for
+// transaction support.
+type Transaction struct {
+	c  *C.rocksdb_transaction_t
+	db txnDB
+}
+
+func newTransaction(c *C.rocksdb_transaction_t, db txnDB) *Transaction {
+	return &Transaction{c: c, db: db}
+}
+
+// SetName sets the name of the transaction, which is required before
+// Prepare. The name should be unique among all the transactions of the
+// database.
+func (txn *Transaction) SetName(name string) error {
+	var (
+		cErr  *C.char
+		cName = stringToChar(name)
+	)
+	txn.db.RLock()
+	if !txn.db.IsOpened() {
+		txn.db.RUnlock()
+		return errDBClosed
+	}
+	C.rocksdb_transaction_set_name(txn.c, cName, C.size_t(len(name)), &cErr)
+	txn.db.RUnlock()
+	if cErr != nil {
+		defer C.free(unsafe.Pointer(cErr))
+		return newTxnError(C.GoString(cErr))
+	}
+	return nil
+}
+
+// GetName returns the name of the transaction.
+func (txn *Transaction) GetName() string {
+	var cLen C.size_t
+	cName := C.rocksdb_transaction_get_name(txn.c, &cLen)
+	if cName == nil {
+		return ""
+	}
+	defer C.free(unsafe.Pointer(cName))
+	return string(charToByte(cName, cLen))
+}
+
+// Prepare does the first phase of the two phase commit. The transaction
+// must be named by SetName before. After a successful prepare the
+// transaction is persisted in the WAL and will be recovered as a prepared
+// transaction if the process crashed before Commit or Rollback.
+func (txn *Transaction) Prepare() error {
+	var cErr *C.char
+	txn.db.RLock()
+	if !txn.db.IsOpened() {
+		txn.db.RUnlock()
+		return errDBClosed
+	}
+	C.rocksdb_transaction_prepare(txn.c, &cErr)
+	txn.db.RUnlock()
+	if cErr != nil {
+		defer C.free(unsafe.Pointer(cErr))
+		return newTxnError(C.GoString(cErr))
+	}
+	return nil
+}
+
+// Commit commits the transaction to the database.
+func (txn *Transaction) Commit() error {
+	var cErr *C.char
+	txn.db.RLock()
+	if !txn.db.IsOpened() {
+		txn.db.RUnlock()
+		return errDBClosed
+	}
+	C.rocksdb_transaction_commit(txn.c, &cErr)
+	txn.db.RUnlock()
+	if cErr != nil {
+		defer C.free(unsafe.Pointer(cErr))
+		return newTxnError(C.GoString(cErr))
+	}
+	return nil
+}
+
+// Rollback discards all the changes made in the transaction.
+func (txn *Transaction) Rollback() error {
+	var cErr *C.char
+	txn.db.RLock()
+	if !txn.db.IsOpened() {
+		txn.db.RUnlock()
+		return errDBClosed
+	}
+	C.rocksdb_transaction_rollback(txn.c, &cErr)
+	txn.db.RUnlock()
+	if cErr != nil {
+		defer C.free(unsafe.Pointer(cErr))
+		return newTxnError(C.GoString(cErr))
+	}
+	return nil
+}
+
+// Get returns the data associated with the key from the database given this
+// transaction, including the writes made in this transaction.
+func (txn *Transaction) Get(opts *ReadOptions, key []byte) (*Slice, error) {
+	var (
+		cErr    *C.char
+		cValLen C.size_t
+		cKey    = byteToChar(key)
+	)
+	txn.db.RLock()
+	if !txn.db.IsOpened() {
+		txn.db.RUnlock()
+		return nil, errDBClosed
+	}
+	cValue := C.rocksdb_transaction_get(txn.c, opts.c, cKey, C.size_t(len(key)), &cValLen, &cErr)
+	txn.db.RUnlock()
+	if cErr != nil {
+		defer C.free(unsafe.Pointer(cErr))
+		return nil, newTxnError(C.GoString(cErr))
+	}
+	return NewSlice(cValue, cValLen), nil
+}
+
+// GetCF returns the data associated with the key from the database and
+// column family given this transaction.
+func (txn *Transaction) GetCF(opts *ReadOptions, cf *ColumnFamilyHandle, key []byte) (*Slice, error) {
+	var (
+		cErr    *C.char
+		cValLen C.size_t
+		cKey    = byteToChar(key)
+	)
+	txn.db.RLock()
+	if !txn.db.IsOpened() {
+		txn.db.RUnlock()
+		return nil, errDBClosed
+	}
+	cValue := C.rocksdb_transaction_get_cf(txn.c, opts.c, cf.c, cKey, C.size_t(len(key)), &cValLen, &cErr)
+	txn.db.RUnlock()
+	if cErr != nil {
+		defer C.free(unsafe.Pointer(cErr))
+		return nil, newTxnError(C.GoString(cErr))
+	}
+	return NewSlice(cValue, cValLen), nil
+}
+
+// GetForUpdate is like Get but also puts an exclusive lock on the key, so
+// no other transaction can write it until this transaction is committed or
+// rolled back.
+func (txn *Transaction) GetForUpdate(opts *ReadOptions, key []byte) (*Slice, error) {
+	var (
+		cErr    *C.char
+		cValLen C.size_t
+		cKey    = byteToChar(key)
+	)
+	txn.db.RLock()
+	if !txn.db.IsOpened() {
+		txn.db.RUnlock()
+		return nil, errDBClosed
+	}
+	cValue := C.rocksdb_transaction_get_for_update(txn.c, opts.c, cKey, C.size_t(len(key)), &cValLen, boolToChar(true), &cErr)
+	txn.db.RUnlock()
+	if cErr != nil {
+		defer C.free(unsafe.Pointer(cErr))
+		return nil, newTxnError(C.GoString(cErr))
+	}
+	return NewSlice(cValue, cValLen), nil
+}
+
+// GetForUpdateCF is like GetForUpdate but on the given column family.
+func (txn *Transaction) GetForUpdateCF(opts *ReadOptions, cf *ColumnFamilyHandle, key []byte) (*Slice, error) {
+	var (
+		cErr    *C.char
+		cValLen C.size_t
+		cKey    = byteToChar(key)
+	)
+	txn.db.RLock()
+	if !txn.db.IsOpened() {
+		txn.db.RUnlock()
+		return nil, errDBClosed
+	}
+	cValue := C.rocksdb_transaction_get_for_update_cf(txn.c, opts.c, cf.c, cKey, C.size_t(len(key)), &cValLen, boolToChar(true), &cErr)
+	txn.db.RUnlock()
+	if cErr != nil {
+		defer C.free(unsafe.Pointer(cErr))
+		return nil, newTxnError(C.GoString(cErr))
+	}
+	return NewSlice(cValue, cValLen), nil
+}
+
+// Put writes data associated with a key to the transaction.
+func (txn *Transaction) Put(key, value []byte) error {
+	var (
+		cErr   *C.char
+		cKey   = byteToChar(key)
+		cValue = byteToChar(value)
+	)
+	txn.db.RLock()
+	if !txn.db.IsOpened() {
+		txn.db.RUnlock()
+		return errDBClosed
+	}
+	C.rocksdb_transaction_put(txn.c, cKey, C.size_t(len(key)), cValue, C.size_t(len(value)), &cErr)
+	txn.db.RUnlock()
+	if cErr != nil {
+		defer C.free(unsafe.Pointer(cErr))
+		return newTxnError(C.GoString(cErr))
+	}
+	return nil
+}
+
+// PutCF writes data associated with a key to the transaction in a column family.
+func (txn *Transaction) PutCF(cf *ColumnFamilyHandle, key, value []byte) error {
+	var (
+		cErr   *C.char
+		cKey   = byteToChar(key)
+		cValue = byteToChar(value)
+	)
+	txn.db.RLock()
+	if !txn.db.IsOpened() {
+		txn.db.RUnlock()
+		return errDBClosed
+	}
+	C.rocksdb_transaction_put_cf(txn.c, cf.c, cKey, C.size_t(len(key)), cValue, C.size_t(len(value)), &cErr)
+	txn.db.RUnlock()
+	if cErr != nil {
+		defer C.free(unsafe.Pointer(cErr))
+		return newTxnError(C.GoString(cErr))
+	}
+	return nil
+}
+
+// Merge queues a merge of "value" with the existing value of "key" in the
+// transaction.
+func (txn *Transaction) Merge(key, value []byte) error {
+	var (
+		cErr   *C.char
+		cKey   = byteToChar(key)
+		cValue = byteToChar(value)
+	)
+	txn.db.RLock()
+	if !txn.db.IsOpened() {
+		txn.db.RUnlock()
+		return errDBClosed
+	}
+	C.rocksdb_transaction_merge(txn.c, cKey, C.size_t(len(key)), cValue, C.size_t(len(value)), &cErr)
+	txn.db.RUnlock()
+	if cErr != nil {
+		defer C.free(unsafe.Pointer(cErr))
+		return newTxnError(C.GoString(cErr))
+	}
+	return nil
+}
+
+// MergeCF queues a merge of "value" with the existing value of "key" in a
+// column family in the transaction.
+func (txn *Transaction) MergeCF(cf *ColumnFamilyHandle, key, value []byte) error {
+	var (
+		cErr   *C.char
+		cKey   = byteToChar(key)
+		cValue = byteToChar(value)
+	)
+	txn.db.RLock()
+	if !txn.db.IsOpened() {
+		txn.db.RUnlock()
+		return errDBClosed
+	}
+	C.rocksdb_transaction_merge_cf(txn.c, cf.c, cKey, C.size_t(len(key)), cValue, C.size_t(len(value)), &cErr)
+	txn.db.RUnlock()
+	if cErr != nil {
+		defer C.free(unsafe.Pointer(cErr))
+		return newTxnError(C.GoString(cErr))
+	}
+	return nil
+}
+
+// Delete removes the data associated with the key in the transaction.
+func (txn *Transaction) Delete(key []byte) error {
+	var (
+		cErr *C.char
+		cKey = byteToChar(key)
+	)
+	txn.db.RLock()
+	if !txn.db.IsOpened() {
+		txn.db.RUnlock()
+		return errDBClosed
+	}
+	C.rocksdb_transaction_delete(txn.c, cKey, C.size_t(len(key)), &cErr)
+	txn.db.RUnlock()
+	if cErr != nil {
+		defer C.free(unsafe.Pointer(cErr))
+		return newTxnError(C.GoString(cErr))
+	}
+	return nil
+}
+
+// DeleteCF removes the data associated with the key in a column family in
+// the transaction.
+func (txn *Transaction) DeleteCF(cf *ColumnFamilyHandle, key []byte) error {
+	var (
+		cErr *C.char
+		cKey = byteToChar(key)
+	)
+	txn.db.RLock()
+	if !txn.db.IsOpened() {
+		txn.db.RUnlock()
+		return errDBClosed
+	}
+	C.rocksdb_transaction_delete_cf(txn.c, cf.c, cKey, C.size_t(len(key)), &cErr)
+	txn.db.RUnlock()
+	if cErr != nil {
+		defer C.free(unsafe.Pointer(cErr))
+		return newTxnError(C.GoString(cErr))
+	}
+	return nil
+}
+
+// GetSnapshot returns the snapshot taken by the transaction if
+// TransactionOptions.SetSetSnapshot or OptimisticTransactionOptions.SetSetSnapshot
+// is enabled. The snapshot can be used in ReadOptions.SetSnapshot to read in
+// the view of the transaction, if the transaction has no snapshot, the reads
+// with it see the latest data. The snapshot is owned by the transaction,
+// Release only frees the returned handle.
+func (txn *Transaction) GetSnapshot() *Snapshot {
+	cSnap := C.rocksdb_transaction_get_snapshot(txn.c)
+	return &Snapshot{c: cSnap, handleOnly: true}
+}
+
+// NewIterator returns an Iterator over the database and the writes made in
+// this transaction that uses the ReadOptions given.
+// iterator should be protected by rlock by caller since it may hold during iterating
+func (txn *Transaction) NewIterator(opts *ReadOptions) (*Iterator, error) {
+	if !txn.db.IsOpened() {
+		return nil, errDBClosed
+	}
+	cIter := C.rocksdb_transaction_create_iterator(txn.c, opts.c)
+	return NewNativeIterator(unsafe.Pointer(cIter)), nil
+}
+
+// NewIteratorCF returns an Iterator over the column family and the writes
+// made in this transaction that uses the ReadOptions given.
+func (txn *Transaction) NewIteratorCF(opts *ReadOptions, cf *ColumnFamilyHandle) (*Iterator, error) {
+	if !txn.db.IsOpened() {
+		return nil, errDBClosed
+	}
+	cIter := C.rocksdb_transaction_create_iterator_cf(txn.c, opts.c, cf.c)
+	return NewNativeIterator(unsafe.Pointer(cIter)), nil
+}
+
+// Destroy deallocates the transaction object. An uncommitted transaction
+// will be rolled back.
+func (txn *Transaction) Destroy() {
+	txn.db.RLock()
+	// the transaction references the db internally, after the db is
+	// closed we can only leak it.
+	if txn.db.IsOpened() {
+		C.rocksdb_transaction_destroy(txn.c)
+	}
+	txn.db.RUnlock()
+	txn.c = nil
+}
@@ -0,0 +1,188 @@
+package gorocksdb
+
+// #include <stdlib.h>
+// #include "rocksdb/c.h"
+import "C"
+import (
+	"errors"
+	"fmt"
+	"os"
+	"unsafe"
+)
+
+// WALPurgedError is returned by GetUpdatesSince if the write-ahead logs
+// containing the requested sequence number have been purged. The logs are
+// kept longer by SetWALTtlSeconds or SetWalSizeLimitMb.
+type WALPurgedError struct {
+	// Requested is the requested sequence number.
+	Requested uint64
+	// First is the first sequence number still in the logs, 0 if unknown.
+	First uint64
+}
+
+func (e *WALPurgedError) Error() string {
+	if e.First == 0 {
+		return fmt.Sprintf("wal since sequence %v has been purged", e.Requested)
+	}
+	return fmt.Sprintf("wal since sequence %v has been purged, the first available sequence is %v",
+		e.Requested, e.First)
+}
+
+// IsWALPurged returns true if the error is a *WALPurgedError.
+func IsWALPurged(err error) bool {
+	_, ok := err.(*WALPurgedError)
+	return ok
+}
+
+// TransactionLogIterator is used to read the write batches from the
+// write-ahead logs in the order of the sequence numbers, created by
+// DB.GetUpdatesSince.
+//
+// For example:
+//
+//	iter, err := db.GetUpdatesSince(seq)
+//	if err != nil {
+//	    return err
+//	}
+//	defer iter.Destroy()
+//
+//	for ; iter.Valid(); iter.Next() {
+//	    wb, seq := iter.GetBatch()
+//	    ...
+//	    wb.Destroy()
+//	}
+//
+//	if err := iter.Err(); err != nil {
+//	    return err
+//	}
+type TransactionLogIterator struct {
+	c *C.rocksdb_wal_iterator_t
+}
+
+// NewNativeTransactionLogIterator creates a TransactionLogIterator object.
+func NewNativeTransactionLogIterator(c unsafe.Pointer) *TransactionLogIterator {
+	return &TransactionLogIterator{(*C.rocksdb_wal_iterator_t)(c)}
+}
+
+// Valid returns false if the iterator reaches the end of the logs or
+// an error happens. The logs written after the iterator reaches the end
+// can not be read by the iterator, a new iterator should be created.
+func (iter *TransactionLogIterator) Valid() bool {
+	return C.rocksdb_wal_iter_valid(iter.c) != 0
+}
+
+// Next moves the iterator to the next write batch.
+func (iter *TransactionLogIterator) Next() {
+	C.rocksdb_wal_iter_next(iter.c)
+}
+
+// GetBatch returns the current write batch and the sequence number of its
+// first record. The batch should be destroyed by the caller and can be
+// decoded by WriteBatch.NewIterator.
+func (iter *TransactionLogIterator) GetBatch() (*WriteBatch, uint64) {
+	var cSeq C.uint64_t
+	cBatch := C.rocksdb_wal_iter_get_batch(iter.c, &cSeq)
+	return NewNativeWriteBatch(cBatch), uint64(cSeq)
+}
+
+// Err returns nil if no errors happened during iteration, or the actual
+// error otherwise.
+func (iter *TransactionLogIterator) Err() error {
+	var cErr *C.char
+	C.rocksdb_wal_iter_status(iter.c, &cErr)
+	if cErr != nil {
+		defer C.free(unsafe.Pointer(cErr))
+		return errors.New(C.GoString(cErr))
+	}
+	return nil
+}
+
+// Destroy deallocates the TransactionLogIterator object.
+func (iter *TransactionLogIterator) Destroy() {
+	C.rocksdb_wal_iter_destroy(iter.c)
+	iter.c = nil
+}
+
+// GetLatestSequenceNumber returns the sequence number of the most recent
+// write in the db.
+func (db *DB) GetLatestSequenceNumber() uint64 {
+	db.RLock()
+	if db.opened == 0 {
+		db.RUnlock()
+		return 0
+	}
+	seq := C.rocksdb_get_latest_sequence_number(db.c)
+	db.RUnlock()
+	return uint64(seq)
+}
+
+// GetUpdatesSince returns an iterator positioned at the write batch which
+// contains the sequence number seq. If the logs containing seq have been
+// purged, that is seq is before the first batch of the logs, a
+// *WALPurgedError is returned. The logs purged after the check are found by
+// the sequence number of the first batch returned by the iterator.
+// The iterator should be protected by rlock by caller, it is not released
+// when the database is closed. GetUpdatesSince takes the rlock itself, so
+// it's not called with the rlock held.
+func (db *DB) GetUpdatesSince(seq uint64) (*TransactionLogIterator, error) {
+	var cErr *C.char
+	db.RLock()
+	if db.opened == 0 {
+		db.RUnlock()
+		return nil, errDBClosed
+	}
+	latest := uint64(C.rocksdb_get_latest_sequence_number(db.c))
+	first, err := db.firstWALSequence()
+	if err != nil {
+		db.RUnlock()
+		return nil, err
+	}
+	// the sequence numbers start from 1.
+	start := seq
+	if start == 0 {
+		start = 1
+	}
+	if start <= latest && (first == 0 || start < first) {
+		db.RUnlock()
+		return nil, &WALPurgedError{Requested: seq, First: first}
+	}
+	cIter := C.rocksdb_get_updates_since(db.c, C.uint64_t(seq), nil, &cErr)
+	db.RUnlock()
+	if cErr != nil {
+		defer C.free(unsafe.Pointer(cErr))
+		return nil, errors.New(C.GoString(cErr))
+	}
+
+	iter := NewNativeTransactionLogIterator(unsafe.Pointer(cIter))
+	if !iter.Valid() {
+		if err := iter.Err(); err != nil {
+			iter.Destroy()
+			return nil, err
+		}
+	}
+	return iter, nil
+}
+
+// firstWALSequence returns the sequence number of the first write batch in
+// the write-ahead logs, or 0 if the logs are empty. The batch is read from
+// the first log file which is not empty, the files purged meanwhile are
+// skipped.
+func (db *DB) firstWALSequence() (uint64, error) {
+	files, err := listWALFiles(db.walDir())
+	if err != nil {
+		return 0, err
+	}
+	for _, f := range files {
+		seq, err := walStartSequence(f)
+		if os.IsNotExist(err) {
+			continue
+		}
+		if err != nil {
+			return 0, fmt.Errorf("read wal %v: %v", f.path, err)
+		}
+		if seq > 0 {
+			return seq, nil
+		}
+	}
+	return 0, nil
+}
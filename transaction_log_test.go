@@ -0,0 +1,81 @@
+package gorocksdb
+
+import (
+	"testing"
+
+	"github.com/facebookgo/ensure"
+)
+
+func TestGetUpdatesSince(t *testing.T) {
+	db := newTestDB(t, "TestGetUpdatesSince", nil)
+	defer db.Close()
+
+	wo := NewDefaultWriteOptions()
+	ensure.Nil(t, db.Put(wo, []byte("key1"), []byte("val1")))
+	wb := NewWriteBatch()
+	wb.Put([]byte("key2"), []byte("val2"))
+	wb.Delete([]byte("key1"))
+	ensure.Nil(t, db.Write(wo, wb))
+	wb.Destroy()
+	ensure.DeepEqual(t, db.GetLatestSequenceNumber(), uint64(3))
+
+	iter, err := db.GetUpdatesSince(2)
+	ensure.Nil(t, err)
+	defer iter.Destroy()
+	ensure.True(t, iter.Valid())
+	batch, seq := iter.GetBatch()
+	ensure.DeepEqual(t, seq, uint64(2))
+	ensure.DeepEqual(t, batch.Count(), 2)
+	records := batch.NewIterator()
+	ensure.True(t, records.Next())
+	ensure.DeepEqual(t, records.Record().Type, WriteBatchRecordTypeValue)
+	ensure.DeepEqual(t, records.Record().Key, []byte("key2"))
+	ensure.True(t, records.Next())
+	ensure.DeepEqual(t, records.Record().Type, WriteBatchRecordTypeDeletion)
+	ensure.DeepEqual(t, records.Record().Key, []byte("key1"))
+	ensure.False(t, records.Next())
+	batch.Destroy()
+
+	iter.Next()
+	ensure.False(t, iter.Valid())
+	ensure.Nil(t, iter.Err())
+
+	// the first batch of the returned iterator can be read.
+	iter2, err := db.GetUpdatesSince(0)
+	ensure.Nil(t, err)
+	defer iter2.Destroy()
+	ensure.True(t, iter2.Valid())
+	batch, seq = iter2.GetBatch()
+	ensure.DeepEqual(t, seq, uint64(1))
+	ensure.DeepEqual(t, batch.Count(), 1)
+	records = batch.NewIterator()
+	ensure.True(t, records.Next())
+	ensure.DeepEqual(t, records.Record().Key, []byte("key1"))
+	ensure.DeepEqual(t, records.Record().Value, []byte("val1"))
+	batch.Destroy()
+}
+
+func TestGetUpdatesSincePurged(t *testing.T) {
+	db := newTestDB(t, "TestGetUpdatesSincePurged", nil)
+	defer db.Close()
+
+	wo := NewDefaultWriteOptions()
+	ensure.Nil(t, db.Put(wo, []byte("key1"), []byte("val1")))
+	// the log is deleted after flush since the logs are not archived.
+	fo := NewDefaultFlushOptions()
+	defer fo.Destroy()
+	ensure.Nil(t, db.Flush(fo))
+	ensure.Nil(t, db.Put(wo, []byte("key2"), []byte("val2")))
+
+	_, err := db.GetUpdatesSince(1)
+	ensure.True(t, IsWALPurged(err))
+	ensure.DeepEqual(t, err.(*WALPurgedError).First, uint64(2))
+
+	iter, err := db.GetUpdatesSince(2)
+	ensure.Nil(t, err)
+	defer iter.Destroy()
+	ensure.True(t, iter.Valid())
+	batch, seq := iter.GetBatch()
+	ensure.DeepEqual(t, seq, uint64(2))
+	batch.Destroy()
+}
@@ -0,0 +1,418 @@
+package gorocksdb
+
+// #include <stdlib.h>
+// #include "rocksdb/c.h"
+import "C"
+import (
+	"errors"
+	"sync"
+	"sync/atomic"
+	"unsafe"
+)
+
+// TransactionDB is a reusable handle to a RocksDB database with pessimistic
+// transaction support, created by OpenTransactionDb.
+type TransactionDB struct {
+	// lock protect the read from closed engine, transactions begun
+	// from this db hold the read lock during each operation.
+	sync.RWMutex
+	c         *C.rocksdb_transactiondb_t
+	name      string
+	opts      *Options
+	txnDBOpts *TransactionDBOptions
+	opened    int32
+}
+
+// OpenTransactionDb opens a transactional database with the specified options.
+func OpenTransactionDb(opts *Options, txnDBOpts *TransactionDBOptions, name string) (*TransactionDB, error) {
+	var (
+		cErr  *C.char
+		cName = C.CString(name)
+	)
+	defer C.free(unsafe.Pointer(cName))
+	db := C.rocksdb_transactiondb_open(opts.c, txnDBOpts.c, cName, &cErr)
+	if cErr != nil {
+		defer C.free(unsafe.Pointer(cErr))
+		return nil, errors.New(C.GoString(cErr))
+	}
+	return &TransactionDB{
+		name:      name,
+		c:         db,
+		opts:      opts,
+		txnDBOpts: txnDBOpts,
+		opened:    int32(1),
+	}, nil
+}
+
+// OpenTransactionDbColumnFamilies opens a transactional database with the
+// specified column families.
+func OpenTransactionDbColumnFamilies(
+	opts *Options,
+	txnDBOpts *TransactionDBOptions,
+	name string,
+	cfNames []string,
+	cfOpts []*Options,
+) (*TransactionDB, []*ColumnFamilyHandle, error) {
+	numColumnFamilies := len(cfNames)
+	if numColumnFamilies != len(cfOpts) {
+		return nil, nil, errors.New("must provide the same number of column family names and options")
+	}
+
+	cName := C.CString(name)
+	defer C.free(unsafe.Pointer(cName))
+
+	cNames := make([]*C.char, numColumnFamilies)
+	for i, s := range cfNames {
+		cNames[i] = C.CString(s)
+	}
+	defer func() {
+		for _, s := range cNames {
+			C.free(unsafe.Pointer(s))
+		}
+	}()
+
+	cOpts := make([]*C.rocksdb_options_t, numColumnFamilies)
+	for i, o := range cfOpts {
+		cOpts[i] = o.c
+	}
+
+	cHandles := make([]*C.rocksdb_column_family_handle_t, numColumnFamilies)
+
+	var cErr *C.char
+	db := C.rocksdb_transactiondb_open_column_families(
+		opts.c,
+		txnDBOpts.c,
+		cName,
+		C.int(numColumnFamilies),
+		&cNames[0],
+		&cOpts[0],
+		&cHandles[0],
+		&cErr,
+	)
+	if cErr != nil {
+		defer C.free(unsafe.Pointer(cErr))
+		return nil, nil, errors.New(C.GoString(cErr))
+	}
+
+	cfHandles := make([]*ColumnFamilyHandle, numColumnFamilies)
+	for i, c := range cHandles {
+		cfHandles[i] = NewNativeColumnFamilyHandle(c)
+	}
+
+	return &TransactionDB{
+		name:      name,
+		c:         db,
+		opts:      opts,
+		txnDBOpts: txnDBOpts,
+		opened:    int32(1),
+	}, cfHandles, nil
+}
+
+// Name returns the name of the database.
+func (db *TransactionDB) Name() string {
+	return db.name
+}
+
+func (db *TransactionDB) IsOpened() bool {
+	return atomic.LoadInt32(&db.opened) != 0
+}
+
+// TransactionBegin begins a new transaction with the WriteOptions and
+// TransactionOptions given. If oldTxn is not nil, it is reused instead of
+// allocating a new transaction.
+func (db *TransactionDB) TransactionBegin(opts *WriteOptions, txnOpts *TransactionOptions, oldTxn *Transaction) (*Transaction, error) {
+	db.RLock()
+	defer db.RUnlock()
+	if db.opened == 0 {
+		return nil, errDBClosed
+	}
+	if oldTxn != nil {
+		cTxn := C.rocksdb_transaction_begin(db.c, opts.c, txnOpts.c, oldTxn.c)
+		oldTxn.c = cTxn
+		oldTxn.db = db
+		return oldTxn, nil
+	}
+	cTxn := C.rocksdb_transaction_begin(db.c, opts.c, txnOpts.c, nil)
+	return newTransaction(cTxn, db), nil
+}
+
+// GetPreparedTransactions returns the transactions which were prepared but
+// neither committed nor rolled back before the database was closed or
+// crashed. They are recovered on open and should be committed or rolled
+// back, and then destroyed by the caller.
+func (db *TransactionDB) GetPreparedTransactions() ([]*Transaction, error) {
+	var cCnt C.size_t
+	db.RLock()
+	defer db.RUnlock()
+	if db.opened == 0 {
+		return nil, errDBClosed
+	}
+	cTxns := C.rocksdb_transactiondb_get_prepared_transactions(db.c, &cCnt)
+	if cTxns == nil {
+		return nil, nil
+	}
+	defer C.free(unsafe.Pointer(cTxns))
+	cnt := int(cCnt)
+	cTxnsArr := (*[1 << 30]*C.rocksdb_transaction_t)(unsafe.Pointer(cTxns))[:cnt:cnt]
+	txns := make([]*Transaction, cnt)
+	for i, c := range cTxnsArr {
+		txns[i] = newTransaction(c, db)
+	}
+	return txns, nil
+}
+
+// Get returns the data associated with the key from the database.
+func (db *TransactionDB) Get(opts *ReadOptions, key []byte) (*Slice, error) {
+	var (
+		cErr    *C.char
+		cValLen C.size_t
+		cKey    = byteToChar(key)
+	)
+	db.RLock()
+	if db.opened == 0 {
+		db.RUnlock()
+		return nil, errDBClosed
+	}
+	cValue := C.rocksdb_transactiondb_get(db.c, opts.c, cKey, C.size_t(len(key)), &cValLen, &cErr)
+	db.RUnlock()
+	if cErr != nil {
+		defer C.free(unsafe.Pointer(cErr))
+		return nil, errors.New(C.GoString(cErr))
+	}
+	return NewSlice(cValue, cValLen), nil
+}
+
+// GetCF returns the data associated with the key from the database and column family.
+func (db *TransactionDB) GetCF(opts *ReadOptions, cf *ColumnFamilyHandle, key []byte) (*Slice, error) {
+	var (
+		cErr    *C.char
+		cValLen C.size_t
+		cKey    = byteToChar(key)
+	)
+	db.RLock()
+	if db.opened == 0 {
+		db.RUnlock()
+		return nil, errDBClosed
+	}
+	cValue := C.rocksdb_transactiondb_get_cf(db.c, opts.c, cf.c, cKey, C.size_t(len(key)), &cValLen, &cErr)
+	db.RUnlock()
+	if cErr != nil {
+		defer C.free(unsafe.Pointer(cErr))
+		return nil, errors.New(C.GoString(cErr))
+	}
+	return NewSlice(cValue, cValLen), nil
+}
+
+// Put writes data associated with a key to the database.
+func (db *TransactionDB) Put(opts *WriteOptions, key, value []byte) error {
+	var (
+		cErr   *C.char
+		cKey   = byteToChar(key)
+		cValue = byteToChar(value)
+	)
+	db.RLock()
+	if db.opened == 0 {
+		db.RUnlock()
+		return errDBClosed
+	}
+	C.rocksdb_transactiondb_put(db.c, opts.c, cKey, C.size_t(len(key)), cValue, C.size_t(len(value)), &cErr)
+	db.RUnlock()
+	if cErr != nil {
+		defer C.free(unsafe.Pointer(cErr))
+		return errors.New(C.GoString(cErr))
+	}
+	return nil
+}
+
+// PutCF writes data associated with a key to the database and column family.
+func (db *TransactionDB) PutCF(opts *WriteOptions, cf *ColumnFamilyHandle, key, value []byte) error {
+	var (
+		cErr   *C.char
+		cKey   = byteToChar(key)
+		cValue = byteToChar(value)
+	)
+	db.RLock()
+	if db.opened == 0 {
+		db.RUnlock()
+		return errDBClosed
+	}
+	C.rocksdb_transactiondb_put_cf(db.c, opts.c, cf.c, cKey, C.size_t(len(key)), cValue, C.size_t(len(value)), &cErr)
+	db.RUnlock()
+	if cErr != nil {
+		defer C.free(unsafe.Pointer(cErr))
+		return errors.New(C.GoString(cErr))
+	}
+	return nil
+}
+
+// Delete removes the data associated with the key from the database.
+func (db *TransactionDB) Delete(opts *WriteOptions, key []byte) error {
+	var (
+		cErr *C.char
+		cKey = byteToChar(key)
+	)
+	db.RLock()
+	if db.opened == 0 {
+		db.RUnlock()
+		return errDBClosed
+	}
+	C.rocksdb_transactiondb_delete(db.c, opts.c, cKey, C.size_t(len(key)), &cErr)
+	db.RUnlock()
+	if cErr != nil {
+		defer C.free(unsafe.Pointer(cErr))
+		return errors.New(C.GoString(cErr))
+	}
+	return nil
+}
+
+// DeleteCF removes the data associated with the key from the database and column family.
+func (db *TransactionDB) DeleteCF(opts *WriteOptions, cf *ColumnFamilyHandle, key []byte) error {
+	var (
+		cErr *C.char
+		cKey = byteToChar(key)
+	)
+	db.RLock()
+	if db.opened == 0 {
+		db.RUnlock()
+		return errDBClosed
+	}
+	C.rocksdb_transactiondb_delete_cf(db.c, opts.c, cf.c, cKey, C.size_t(len(key)), &cErr)
+	db.RUnlock()
+	if cErr != nil {
+		defer C.free(unsafe.Pointer(cErr))
+		return errors.New(C.GoString(cErr))
+	}
+	return nil
+}
+
+// Merge merges the data associated with the key with the actual data in the database.
+func (db *TransactionDB) Merge(opts *WriteOptions, key []byte, value []byte) error {
+	var (
+		cErr   *C.char
+		cKey   = byteToChar(key)
+		cValue = byteToChar(value)
+	)
+	db.RLock()
+	if db.opened == 0 {
+		db.RUnlock()
+		return errDBClosed
+	}
+	C.rocksdb_transactiondb_merge(db.c, opts.c, cKey, C.size_t(len(key)), cValue, C.size_t(len(value)), &cErr)
+	db.RUnlock()
+	if cErr != nil {
+		defer C.free(unsafe.Pointer(cErr))
+		return errors.New(C.GoString(cErr))
+	}
+	return nil
+}
+
+// MergeCF merges the data associated with the key with the actual data in the
+// database and column family.
+func (db *TransactionDB) MergeCF(opts *WriteOptions, cf *ColumnFamilyHandle, key []byte, value []byte) error {
+	var (
+		cErr   *C.char
+		cKey   = byteToChar(key)
+		cValue = byteToChar(value)
+	)
+	db.RLock()
+	if db.opened == 0 {
+		db.RUnlock()
+		return errDBClosed
+	}
+	C.rocksdb_transactiondb_merge_cf(db.c, opts.c, cf.c, cKey, C.size_t(len(key)), cValue, C.size_t(len(value)), &cErr)
+	db.RUnlock()
+	if cErr != nil {
+		defer C.free(unsafe.Pointer(cErr))
+		return errors.New(C.GoString(cErr))
+	}
+	return nil
+}
+
+// Write writes a WriteBatch to the database outside of any transaction.
+func (db *TransactionDB) Write(opts *WriteOptions, batch *WriteBatch) error {
+	var cErr *C.char
+	db.RLock()
+	if db.opened == 0 {
+		db.RUnlock()
+		return errDBClosed
+	}
+	C.rocksdb_transactiondb_write(db.c, opts.c, batch.c, &cErr)
+	db.RUnlock()
+	if cErr != nil {
+		defer C.free(unsafe.Pointer(cErr))
+		return errors.New(C.GoString(cErr))
+	}
+	return nil
+}
+
+// NewIterator returns an Iterator over the the database that uses the
+// ReadOptions given.
+// iterator should be protected by rlock by caller since it may hold during iterating
+func (db *TransactionDB) NewIterator(opts *ReadOptions) (*Iterator, error) {
+	if db.opened == 0 {
+		return nil, errDBClosed
+	}
+	cIter := C.rocksdb_transactiondb_create_iterator(db.c, opts.c)
+	return NewNativeIterator(unsafe.Pointer(cIter)), nil
+}
+
+// NewIteratorCF returns an Iterator over the the database and column family
+// that uses the ReadOptions given.
+func (db *TransactionDB) NewIteratorCF(opts *ReadOptions, cf *ColumnFamilyHandle) (*Iterator, error) {
+	if db.opened == 0 {
+		return nil, errDBClosed
+	}
+	cIter := C.rocksdb_transactiondb_create_iterator_cf(db.c, opts.c, cf.c)
+	return NewNativeIterator(unsafe.Pointer(cIter)), nil
+}
+
+// NewSnapshot creates a new snapshot of the database.
+// The snapshot must be released by Snapshot.Release or
+// TransactionDB.ReleaseSnapshot.
+func (db *TransactionDB) NewSnapshot() (*Snapshot, error) {
+	if db.opened == 0 {
+		return nil, errDBClosed
+	}
+	cSnap := C.rocksdb_transactiondb_create_snapshot(db.c)
+	return &Snapshot{c: cSnap, txnDB: db}, nil
+}
+
+// ReleaseSnapshot removes the snapshot from the database's list of snapshots.
+func (db *TransactionDB) ReleaseSnapshot(snap *Snapshot) {
+	db.RLock()
+	if db.opened != 0 && snap.c != nil {
+		C.rocksdb_transactiondb_release_snapshot(db.c, snap.c)
+	}
+	db.RUnlock()
+	snap.c = nil
+}
+
+// GetProperty returns the value of a database property.
+func (db *TransactionDB) GetProperty(propName string) string {
+	cprop := C.CString(propName)
+	defer C.free(unsafe.Pointer(cprop))
+	db.RLock()
+	if db.opened == 0 {
+		db.RUnlock()
+		return ""
+	}
+
+	cValue := C.rocksdb_transactiondb_property_value(db.c, cprop)
+	db.RUnlock()
+	defer C.free(unsafe.Pointer(cValue))
+	return C.GoString(cValue)
+}
+
+// Close closes the database. It waits for the in-flight transaction
+// operations and all the transactions should be committed or rolled back
+// before.
+func (db *TransactionDB) Close() {
+	db.Lock()
+	if db.opened == 0 {
+		db.Unlock()
+		return
+	}
+	atomic.StoreInt32(&db.opened, 0)
+	C.rocksdb_transactiondb_close(db.c)
+	db.Unlock()
+}
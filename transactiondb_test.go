@@ -0,0 +1,256 @@
+package gorocksdb
+
+import (
+	"io/ioutil"
+	"testing"
+
+	"github.com/facebookgo/ensure"
+)
+
+func TestOpenTransactionDb(t *testing.T) {
+	db := newTestTransactionDB(t, "TestOpenTransactionDb", nil)
+	defer db.Close()
+}
+
+func TestTransactionDBCRUD(t *testing.T) {
+	db := newTestTransactionDB(t, "TestTransactionDBCRUD", nil)
+	defer db.Close()
+
+	var (
+		givenKey     = []byte("hello")
+		givenVal1    = []byte("world1")
+		givenVal2    = []byte("world2")
+		givenTxnKey  = []byte("hello2")
+		givenTxnKey2 = []byte("hello3")
+		givenTxnVal1 = []byte("whatawonderful")
+		wo           = NewDefaultWriteOptions()
+		ro           = NewDefaultReadOptions()
+		to           = NewDefaultTransactionOptions()
+	)
+
+	// create
+	ensure.Nil(t, db.Put(wo, givenKey, givenVal1))
+
+	// retrieve
+	v1, err := db.Get(ro, givenKey)
+	defer v1.Free()
+	ensure.Nil(t, err)
+	ensure.DeepEqual(t, v1.Data(), givenVal1)
+
+	// update
+	ensure.Nil(t, db.Put(wo, givenKey, givenVal2))
+	v2, err := db.Get(ro, givenKey)
+	defer v2.Free()
+	ensure.Nil(t, err)
+	ensure.DeepEqual(t, v2.Data(), givenVal2)
+
+	// delete
+	ensure.Nil(t, db.Delete(wo, givenKey))
+	v3, err := db.Get(ro, givenKey)
+	ensure.Nil(t, err)
+	ensure.True(t, v3.Data() == nil)
+
+	// transaction
+	txn, err := db.TransactionBegin(wo, to, nil)
+	ensure.Nil(t, err)
+	defer txn.Destroy()
+	// create
+	ensure.Nil(t, txn.Put(givenTxnKey, givenTxnVal1))
+	v4, err := txn.Get(ro, givenTxnKey)
+	defer v4.Free()
+	ensure.Nil(t, err)
+	ensure.DeepEqual(t, v4.Data(), givenTxnVal1)
+
+	// not visible outside the transaction before commit
+	v5, err := db.Get(ro, givenTxnKey)
+	ensure.Nil(t, err)
+	ensure.True(t, v5.Data() == nil)
+
+	ensure.Nil(t, txn.Commit())
+	v6, err := db.Get(ro, givenTxnKey)
+	defer v6.Free()
+	ensure.Nil(t, err)
+	ensure.DeepEqual(t, v6.Data(), givenTxnVal1)
+
+	// rollback
+	txn2, err := db.TransactionBegin(wo, to, nil)
+	ensure.Nil(t, err)
+	defer txn2.Destroy()
+	ensure.Nil(t, txn2.Put(givenTxnKey2, givenTxnVal1))
+	ensure.Nil(t, txn2.Delete(givenTxnKey))
+	ensure.Nil(t, txn2.Rollback())
+
+	v7, err := db.Get(ro, givenTxnKey2)
+	ensure.Nil(t, err)
+	ensure.True(t, v7.Data() == nil)
+	v8, err := db.Get(ro, givenTxnKey)
+	defer v8.Free()
+	ensure.Nil(t, err)
+	ensure.DeepEqual(t, v8.Data(), givenTxnVal1)
+}
+
+func TestTransactionDBGetForUpdate(t *testing.T) {
+	lockTimeoutMilliSec := int64(50)
+	applyOpts := func(opts *Options, transactionDBOpts *TransactionDBOptions) {
+		transactionDBOpts.SetTransactionLockTimeout(lockTimeoutMilliSec)
+	}
+	db := newTestTransactionDB(t, "TestTransactionDBGetForUpdate", applyOpts)
+	defer db.Close()
+
+	var (
+		givenKey = []byte("hello")
+		givenVal = []byte("world")
+		wo       = NewDefaultWriteOptions()
+		ro       = NewDefaultReadOptions()
+		to       = NewDefaultTransactionOptions()
+	)
+
+	txn, err := db.TransactionBegin(wo, to, nil)
+	ensure.Nil(t, err)
+	defer txn.Destroy()
+
+	v, err := txn.GetForUpdate(ro, givenKey)
+	ensure.Nil(t, err)
+	v.Free()
+
+	// the key is locked by the transaction
+	ensure.NotNil(t, db.Put(wo, givenKey, givenVal))
+
+	ensure.Nil(t, txn.Commit())
+	ensure.Nil(t, db.Put(wo, givenKey, givenVal))
+}
+
+func TestTransactionAfterCloseTransactionDB(t *testing.T) {
+	db := newTestTransactionDB(t, "TestTransactionAfterCloseTransactionDB", nil)
+
+	wo := NewDefaultWriteOptions()
+	to := NewDefaultTransactionOptions()
+	txn, err := db.TransactionBegin(wo, to, nil)
+	ensure.Nil(t, err)
+	ensure.Nil(t, txn.Put([]byte("hello"), []byte("world")))
+	ensure.Nil(t, txn.Rollback())
+	db.Close()
+
+	ensure.DeepEqual(t, txn.Put([]byte("hello"), []byte("world")), errDBClosed)
+	ensure.DeepEqual(t, txn.Commit(), errDBClosed)
+	txn.Destroy()
+	_, err = db.TransactionBegin(wo, to, nil)
+	ensure.DeepEqual(t, err, errDBClosed)
+}
+
+func TestTransactionDBSnapshot(t *testing.T) {
+	db := newTestTransactionDB(t, "TestTransactionDBSnapshot", nil)
+	defer db.Close()
+
+	wo := NewDefaultWriteOptions()
+	ensure.Nil(t, db.Put(wo, []byte("key1"), []byte("val1")))
+	snap, err := db.NewSnapshot()
+	ensure.Nil(t, err)
+	ensure.Nil(t, db.Put(wo, []byte("key1"), []byte("val2")))
+
+	ro := NewDefaultReadOptions()
+	ro.SetSnapshot(snap)
+	v, err := db.Get(ro, []byte("key1"))
+	ensure.Nil(t, err)
+	ensure.DeepEqual(t, v.Data(), []byte("val1"))
+	v.Free()
+	snap.Release()
+}
+
+func TestTransactionGetSnapshot(t *testing.T) {
+	db := newTestTransactionDB(t, "TestTransactionGetSnapshot", nil)
+	defer db.Close()
+
+	wo := NewDefaultWriteOptions()
+	to := NewDefaultTransactionOptions()
+	txn, err := db.TransactionBegin(wo, to, nil)
+	ensure.Nil(t, err)
+	// the handle without a snapshot reads the latest data.
+	ensure.Nil(t, db.Put(wo, []byte("key1"), []byte("val1")))
+	snap := txn.GetSnapshot()
+	ro := NewDefaultReadOptions()
+	ro.SetSnapshot(snap)
+	v, err := db.Get(ro, []byte("key1"))
+	ensure.Nil(t, err)
+	ensure.DeepEqual(t, v.Data(), []byte("val1"))
+	v.Free()
+	snap.Release()
+	txn.Destroy()
+
+	to.SetSetSnapshot(true)
+	txn, err = db.TransactionBegin(wo, to, nil)
+	ensure.Nil(t, err)
+	defer txn.Destroy()
+	snap = txn.GetSnapshot()
+	ensure.NotNil(t, snap)
+	snap.Release()
+}
+
+func TestTransactionDBTwoPhaseCommit(t *testing.T) {
+	dir, err := ioutil.TempDir("", "gorocksdb-TestTransactionDBTwoPhaseCommit")
+	ensure.Nil(t, err)
+
+	opts := NewDefaultOptions()
+	opts.SetCreateIfMissing(true)
+	transactionDBOpts := NewDefaultTransactionDBOptions()
+	db, err := OpenTransactionDb(opts, transactionDBOpts, dir)
+	ensure.Nil(t, err)
+
+	var (
+		givenName = "xid1"
+		givenKey  = []byte("hello")
+		givenVal  = []byte("world")
+		wo        = NewDefaultWriteOptions()
+		ro        = NewDefaultReadOptions()
+		to        = NewDefaultTransactionOptions()
+	)
+
+	txn, err := db.TransactionBegin(wo, to, nil)
+	ensure.Nil(t, err)
+	// prepare requires a name
+	ensure.NotNil(t, txn.Prepare())
+	ensure.Nil(t, txn.SetName(givenName))
+	ensure.DeepEqual(t, txn.GetName(), givenName)
+	ensure.Nil(t, txn.Put(givenKey, givenVal))
+	ensure.Nil(t, txn.Prepare())
+	txn.Destroy()
+	db.Close()
+	// closing twice does nothing.
+	db.Close()
+
+	// the prepared transaction is recovered on reopen
+	db, err = OpenTransactionDb(opts, transactionDBOpts, dir)
+	ensure.Nil(t, err)
+	defer db.Close()
+	v1, err := db.Get(ro, givenKey)
+	ensure.Nil(t, err)
+	ensure.True(t, v1.Data() == nil)
+
+	txns, err := db.GetPreparedTransactions()
+	ensure.Nil(t, err)
+	ensure.DeepEqual(t, len(txns), 1)
+	ensure.DeepEqual(t, txns[0].GetName(), givenName)
+	ensure.Nil(t, txns[0].Commit())
+	txns[0].Destroy()
+
+	v2, err := db.Get(ro, givenKey)
+	defer v2.Free()
+	ensure.Nil(t, err)
+	ensure.DeepEqual(t, v2.Data(), givenVal)
+}
+
+func newTestTransactionDB(t *testing.T, name string, applyOpts func(opts *Options, transactionDBOpts *TransactionDBOptions)) *TransactionDB {
+	dir, err := ioutil.TempDir("", "gorocksdb-"+name)
+	ensure.Nil(t, err)
+
+	opts := NewDefaultOptions()
+	opts.SetCreateIfMissing(true)
+	transactionDBOpts := NewDefaultTransactionDBOptions()
+	if applyOpts != nil {
+		applyOpts(opts, transactionDBOpts)
+	}
+	db, err := OpenTransactionDb(opts, transactionDBOpts, dir)
+	ensure.Nil(t, err)
+
+	return db
+}
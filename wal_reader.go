@@ -0,0 +1,244 @@
+package gorocksdb
+
+import (
+	"encoding/binary"
+	"errors"
+	"hash/crc32"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// The physical format of the rocksdb write-ahead log files, see
+// db/log_format.h. A file is a sequence of 32KB blocks, each block holds
+// a sequence of records of:
+//
+//	checksum (4 bytes), length (2 bytes), type (1 byte), payload
+//
+// The recyclable record types have an extra log number (4 bytes) in the
+// header. A logical record, which is a serialized WriteBatch, may be split
+// into a first, middle* and last physical records.
+const (
+	walBlockSize            = 32768
+	walHeaderSize           = 7
+	walRecyclableHeaderSize = 11
+
+	walZeroType             = 0
+	walFullType             = 1
+	walFirstType            = 2
+	walMiddleType           = 3
+	walLastType             = 4
+	walRecyclableFullType   = 5
+	walRecyclableFirstType  = 6
+	walRecyclableMiddleType = 7
+	walRecyclableLastType   = 8
+)
+
+var (
+	errWALCorrupted = errors.New("corrupted wal record")
+	crc32cTable     = crc32.MakeTable(crc32.Castagnoli)
+)
+
+// walFile is a write-ahead log file of a db.
+type walFile struct {
+	path     string
+	number   uint64
+	size     int64
+	modTime  time.Time
+	archived bool
+}
+
+// listWALFiles lists the live and archived write-ahead log files in the
+// wal dir, sorted by the log number.
+func listWALFiles(walDir string) ([]walFile, error) {
+	seen := make(map[uint64]bool)
+	var files []walFile
+	for i, dir := range []string{walDir, filepath.Join(walDir, "archive")} {
+		infos, err := ioutil.ReadDir(dir)
+		if err != nil {
+			if os.IsNotExist(err) {
+				continue
+			}
+			return nil, err
+		}
+		for _, info := range infos {
+			name := info.Name()
+			if info.IsDir() || !strings.HasSuffix(name, ".log") {
+				continue
+			}
+			number, err := strconv.ParseUint(strings.TrimSuffix(name, ".log"), 10, 64)
+			if err != nil || seen[number] {
+				continue
+			}
+			seen[number] = true
+			files = append(files, walFile{
+				path:     filepath.Join(dir, name),
+				number:   number,
+				size:     info.Size(),
+				modTime:  info.ModTime(),
+				archived: i == 1,
+			})
+		}
+	}
+	sort.Slice(files, func(i, j int) bool { return files[i].number < files[j].number })
+	return files, nil
+}
+
+// walReader reads the serialized write batches from a write-ahead log file.
+type walReader struct {
+	data   []byte
+	number uint64
+	// the offset of the current block
+	block int
+	// the offset in the current block
+	offset int
+}
+
+func newWALReader(f walFile) (*walReader, error) {
+	data, err := readWALFile(f, 0)
+	if err != nil {
+		return nil, err
+	}
+	return &walReader{data: data, number: f.number}, nil
+}
+
+// readWALFile reads the log file, or the first limit bytes of it if limit is
+// positive. A live log which is archived after it's listed is read from the
+// archive sub dir.
+func readWALFile(f walFile, limit int64) ([]byte, error) {
+	file, err := os.Open(f.path)
+	if os.IsNotExist(err) && !f.archived {
+		dir, name := filepath.Split(f.path)
+		file, err = os.Open(filepath.Join(dir, "archive", name))
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	var r io.Reader = file
+	if limit > 0 {
+		r = io.LimitReader(file, limit)
+	}
+	return ioutil.ReadAll(r)
+}
+
+// walStartSequence returns the sequence number of the first write batch in
+// the log file, or 0 if the file is empty. The header of the first batch is
+// always in the first block, so only the first block is read.
+func walStartSequence(f walFile) (uint64, error) {
+	data, err := readWALFile(f, walBlockSize)
+	if err != nil {
+		return 0, err
+	}
+	r := &walReader{data: data, number: f.number}
+	_, payload, err := r.readPhysicalRecord()
+	if err == io.EOF || err == io.ErrUnexpectedEOF {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, err
+	}
+	if len(payload) < kHeader {
+		return 0, errWALCorrupted
+	}
+	return binary.LittleEndian.Uint64(payload[0:8]), nil
+}
+
+// Next returns the next serialized write batch. It returns io.EOF at the
+// end of the file, a truncated record at the tail of the file (which is
+// being written or was not synced) is also treated as the end of the file.
+func (r *walReader) Next() ([]byte, error) {
+	var (
+		record     []byte
+		inFragment bool
+	)
+	for {
+		typ, payload, err := r.readPhysicalRecord()
+		if err == io.EOF || err == io.ErrUnexpectedEOF {
+			return nil, io.EOF
+		}
+		if err != nil {
+			return nil, err
+		}
+		switch {
+		case typ == walFullType || typ == walRecyclableFullType:
+			if inFragment {
+				return nil, errWALCorrupted
+			}
+			return payload, nil
+		case typ == walFirstType || typ == walRecyclableFirstType:
+			if inFragment {
+				return nil, errWALCorrupted
+			}
+			record = append(record[:0], payload...)
+			inFragment = true
+		case typ == walMiddleType || typ == walRecyclableMiddleType:
+			if !inFragment {
+				return nil, errWALCorrupted
+			}
+			record = append(record, payload...)
+		case typ == walLastType || typ == walRecyclableLastType:
+			if !inFragment {
+				return nil, errWALCorrupted
+			}
+			return append(record, payload...), nil
+		default:
+			return nil, errWALCorrupted
+		}
+	}
+}
+
+func (r *walReader) readPhysicalRecord() (byte, []byte, error) {
+	// the trailer of a block which is too small for a header is
+	// filled with zeros.
+	if walBlockSize-r.offset < walHeaderSize {
+		r.block += walBlockSize
+		r.offset = 0
+	}
+	pos := r.block + r.offset
+	if pos >= len(r.data) {
+		return 0, nil, io.EOF
+	}
+	if pos+walHeaderSize > len(r.data) {
+		return 0, nil, io.ErrUnexpectedEOF
+	}
+	header := r.data[pos : pos+walHeaderSize]
+	length := int(binary.LittleEndian.Uint16(header[4:6]))
+	typ := header[6]
+	if typ == walZeroType && length == 0 {
+		// preallocated space which has not been written.
+		return 0, nil, io.EOF
+	}
+	headerSize := walHeaderSize
+	if typ >= walRecyclableFullType && typ <= walRecyclableLastType {
+		headerSize = walRecyclableHeaderSize
+	}
+	if r.offset+headerSize+length > walBlockSize {
+		return 0, nil, errWALCorrupted
+	}
+	end := pos + headerSize + length
+	if end > len(r.data) {
+		return 0, nil, io.ErrUnexpectedEOF
+	}
+	expected := unmaskCRC(binary.LittleEndian.Uint32(header[0:4]))
+	if crc32.Checksum(r.data[pos+6:end], crc32cTable) != expected {
+		return 0, nil, errWALCorrupted
+	}
+	if headerSize == walRecyclableHeaderSize &&
+		uint64(binary.LittleEndian.Uint32(r.data[pos+7:pos+11])) != r.number&0xffffffff {
+		// the stale records of a recycled log file.
+		return 0, nil, io.EOF
+	}
+	r.offset += headerSize + length
+	return typ, r.data[pos+headerSize : end], nil
+}
+
+func unmaskCRC(masked uint32) uint32 {
+	rot := masked - 0xa282ead8
+	return (rot >> 17) | (rot << 15)
+}
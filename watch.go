@@ -0,0 +1,80 @@
+package gorocksdb
+
+import (
+	"bytes"
+	"context"
+)
+
+const watchChanSize = 64
+
+// WatchEvent is a key written under the prefix of a PrefixWatcher.
+type WatchEvent struct {
+	Key   []byte
+	Value []byte
+}
+
+// PrefixWatcher is a stream of the keys written under a prefix, created by
+// DB.WatchPrefix.
+type PrefixWatcher struct {
+	sub *Subscription
+	ch  chan *WatchEvent
+	err error
+}
+
+// WatchPrefix watches the keys written under the prefix after the call, the
+// keys are delivered to the channel returned by PrefixWatcher.Changes.
+// The writes are read from the logs by Subscribe, so every Put of a key
+// under the prefix in the default column family is delivered in the order
+// of the writes, including the updates of the existing keys. The deletions
+// and the merges are not delivered, use Subscribe for them.
+// Like Subscribe, the watcher reports a *ChangeGapError if the logs are
+// deleted before they are read, the db should keep the logs by
+// SetWALTtlSeconds or SetWalSizeLimitMb if the watcher may lag behind.
+// The watcher stops reading while the channel is full, and ends when the
+// context is done or the db is closed.
+// It takes the rlock itself to read the latest sequence number, so it's not
+// called with the rlock held, which deadlocks with a pending Close.
+func (db *DB) WatchPrefix(ctx context.Context, prefix []byte) (*PrefixWatcher, error) {
+	prefix = append([]byte(nil), prefix...)
+	sub, err := db.Subscribe(ctx, db.GetLatestSequenceNumber()+1, func(record *ChangeRecord) bool {
+		return record.Type == WriteBatchRecordTypeValue && record.ColumnFamilyID == 0 &&
+			bytes.HasPrefix(record.Key, prefix)
+	})
+	if err != nil {
+		return nil, err
+	}
+	w := &PrefixWatcher{
+		sub: sub,
+		ch:  make(chan *WatchEvent, watchChanSize),
+	}
+	go w.run(ctx)
+	return w, nil
+}
+
+// Changes returns the channel of the written keys, which is closed when the
+// watcher ends.
+func (w *PrefixWatcher) Changes() <-chan *WatchEvent {
+	return w.ch
+}
+
+// Err returns the error which ends the watcher after the channel is closed,
+// it's nil if the watcher ends by the context.
+func (w *PrefixWatcher) Err() error {
+	return w.err
+}
+
+func (w *PrefixWatcher) run(ctx context.Context) {
+	defer close(w.ch)
+	for record := range w.sub.Changes() {
+		event := &WatchEvent{Key: record.Key, Value: record.Value}
+		select {
+		case w.ch <- event:
+		case <-ctx.Done():
+			// the subscription ends by the same context.
+			for range w.sub.Changes() {
+			}
+			return
+		}
+	}
+	w.err = w.sub.Err()
+}
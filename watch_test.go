@@ -0,0 +1,73 @@
+package gorocksdb
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/facebookgo/ensure"
+)
+
+func TestWatchPrefix(t *testing.T) {
+	db := newTestDB(t, "TestWatchPrefix", nil)
+
+	cf, err := db.CreateColumnFamily(NewDefaultOptions(), "guide")
+	ensure.Nil(t, err)
+	defer cf.Destroy()
+
+	wo := NewDefaultWriteOptions()
+	ensure.Nil(t, db.Put(wo, []byte("event/1"), []byte("val1")))
+
+	w, err := db.WatchPrefix(context.Background(), []byte("event/"))
+	ensure.Nil(t, err)
+
+	ensure.Nil(t, db.Put(wo, []byte("other"), []byte("val")))
+	ensure.Nil(t, db.PutCF(wo, cf, []byte("event/2"), []byte("val")))
+	ensure.Nil(t, db.Put(wo, []byte("event/2"), []byte("val2")))
+	event := <-w.Changes()
+	ensure.DeepEqual(t, event.Key, []byte("event/2"))
+	ensure.DeepEqual(t, event.Value, []byte("val2"))
+
+	// the keys before the last delivered key and the updates are delivered.
+	ensure.Nil(t, db.Delete(wo, []byte("event/1")))
+	ensure.Nil(t, db.Put(wo, []byte("event/10"), []byte("val10")))
+	ensure.Nil(t, db.Put(wo, []byte("event/2"), []byte("val2-2")))
+	event = <-w.Changes()
+	ensure.DeepEqual(t, event.Key, []byte("event/10"))
+	ensure.DeepEqual(t, event.Value, []byte("val10"))
+	event = <-w.Changes()
+	ensure.DeepEqual(t, event.Key, []byte("event/2"))
+	ensure.DeepEqual(t, event.Value, []byte("val2-2"))
+
+	db.Close()
+	for range w.Changes() {
+	}
+	ensure.DeepEqual(t, w.Err(), errDBClosed)
+}
+
+func TestWatchPrefixCancel(t *testing.T) {
+	db := newTestDB(t, "TestWatchPrefixCancel", nil)
+	defer db.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	w, err := db.WatchPrefix(ctx, []byte("event/"))
+	ensure.Nil(t, err)
+	cancel()
+	for range w.Changes() {
+	}
+	ensure.Nil(t, w.Err())
+}
+
+func TestWatchPrefixCloseEmptyDB(t *testing.T) {
+	db := newTestDB(t, "TestWatchPrefixCloseEmptyDB", nil)
+
+	w, err := db.WatchPrefix(context.Background(), []byte("event/"))
+	ensure.Nil(t, err)
+
+	// the sequence of the empty db is 0 before and after the close.
+	time.Sleep(2 * subscribePollInterval)
+	db.Close()
+	for range w.Changes() {
+	}
+	ensure.DeepEqual(t, w.Err(), errDBClosed)
+}
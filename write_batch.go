@@ -1,15 +1,26 @@
 package gorocksdb
 
+// #include <stdlib.h>
 // #include "rocksdb/c.h"
 import "C"
-import "io"
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"unsafe"
+)
 
 // WriteBatch header has an 8-byte sequence number followed by a 4-byte count.
 const kHeader int = 12
 
+var errWriteBatchSavePoints = errors.New("can not append to a write batch with save points")
+
 // WriteBatch is a batching of Puts, Merges and Deletes.
 type WriteBatch struct {
 	c *C.rocksdb_writebatch_t
+	// savePoints is the number of the save points set by SetSavePoint.
+	savePoints int
 }
 
 // NewWriteBatch create a WriteBatch object.
@@ -19,7 +30,7 @@ func NewWriteBatch() *WriteBatch {
 
 // NewNativeWriteBatch create a WriteBatch object.
 func NewNativeWriteBatch(c *C.rocksdb_writebatch_t) *WriteBatch {
-	return &WriteBatch{c}
+	return &WriteBatch{c: c}
 }
 
 // WriteBatchFrom creates a write batch from a serialized WriteBatch.
@@ -76,6 +87,72 @@ func (wb *WriteBatch) DeleteCF(cf *ColumnFamilyHandle, key []byte) {
 	C.rocksdb_writebatch_delete_cf(wb.c, cf.c, cKey, C.size_t(len(key)))
 }
 
+// PutLogData appends a blob of metadata to the batch, which is written to
+// the WAL but not to the db. It can be read from the WAL as a LogData
+// record by WriteBatchIterator.
+func (wb *WriteBatch) PutLogData(blob []byte) {
+	cBlob := byteToChar(blob)
+	C.rocksdb_writebatch_put_log_data(wb.c, cBlob, C.size_t(len(blob)))
+}
+
+// SetSavePoint records the state of the batch for RollbackToSavePoint.
+// It can be called multiple times to set multiple save points.
+func (wb *WriteBatch) SetSavePoint() {
+	C.rocksdb_writebatch_set_save_point(wb.c)
+	wb.savePoints++
+}
+
+// RollbackToSavePoint removes the records appended after the most recent
+// save point, and removes the save point. It fails if there is no save
+// point.
+func (wb *WriteBatch) RollbackToSavePoint() error {
+	var cErr *C.char
+	C.rocksdb_writebatch_rollback_to_save_point(wb.c, &cErr)
+	if cErr != nil {
+		defer C.free(unsafe.Pointer(cErr))
+		return errors.New(C.GoString(cErr))
+	}
+	wb.savePoints--
+	return nil
+}
+
+// PopSavePoint removes the most recent save point without rolling back.
+// It fails if there is no save point.
+func (wb *WriteBatch) PopSavePoint() error {
+	var cErr *C.char
+	C.rocksdb_writebatch_pop_save_point(wb.c, &cErr)
+	if cErr != nil {
+		defer C.free(unsafe.Pointer(cErr))
+		return errors.New(C.GoString(cErr))
+	}
+	wb.savePoints--
+	return nil
+}
+
+// Append appends the records of other to the batch, other is not changed.
+// The C API has no append, so the batch is rebuilt from the serialized
+// records of both batches, which would drop the save points of the batch.
+// It fails if the batch has save points, pop or roll back them first.
+func (wb *WriteBatch) Append(other *WriteBatch) error {
+	if wb.savePoints > 0 {
+		return errWriteBatchSavePoints
+	}
+	src := other.Data()
+	if len(src) <= kHeader {
+		return nil
+	}
+	dst := wb.Data()
+	data := make([]byte, 0, len(dst)+len(src)-kHeader)
+	data = append(data, dst...)
+	data = append(data, src[kHeader:]...)
+	// the count in the header covers the records of both batches.
+	binary.LittleEndian.PutUint32(data[8:kHeader], uint32(wb.Count()+other.Count()))
+	c := C.rocksdb_writebatch_create_from(byteToChar(data), C.size_t(len(data)))
+	C.rocksdb_writebatch_destroy(wb.c)
+	wb.c = c
+	return nil
+}
+
 // Data returns the serialized version of this batch.
 func (wb *WriteBatch) Data() []byte {
 	var cSize C.size_t
@@ -89,6 +166,39 @@ func (wb *WriteBatch) Count() int {
 	return int(C.rocksdb_writebatch_count(wb.c))
 }
 
+// GetDataSize returns the size of the serialized batch in bytes.
+func (wb *WriteBatch) GetDataSize() int {
+	var cSize C.size_t
+	C.rocksdb_writebatch_data(wb.c, &cSize)
+	return int(cSize)
+}
+
+// HasPut returns true if the batch has a Value record.
+func (wb *WriteBatch) HasPut() bool {
+	return wb.hasRecord(WriteBatchRecordTypeValue)
+}
+
+// HasDelete returns true if the batch has a Deletion record, the single
+// deletions and range deletions are not included.
+func (wb *WriteBatch) HasDelete() bool {
+	return wb.hasRecord(WriteBatchRecordTypeDeletion)
+}
+
+// HasMerge returns true if the batch has a Merge record.
+func (wb *WriteBatch) HasMerge() bool {
+	return wb.hasRecord(WriteBatchRecordTypeMerge)
+}
+
+func (wb *WriteBatch) hasRecord(recordType WriteBatchRecordType) bool {
+	iter := wb.NewIterator()
+	for iter.Next() {
+		if iter.Record().Type == recordType {
+			return true
+		}
+	}
+	return false
+}
+
 // NewIterator returns a iterator to iterate over the records in the batch.
 func (wb *WriteBatch) NewIterator() *WriteBatchIterator {
 	data := wb.Data()
@@ -98,9 +208,82 @@ func (wb *WriteBatch) NewIterator() *WriteBatchIterator {
 	return &WriteBatchIterator{data: data[12:]}
 }
 
-// Clear removes all the enqueued Put and Deletes.
+// WriteBatchHandler is called back by WriteBatch.Iterate for the records
+// in the batch. cf is the id of the column family, 0 for the default column
+// family. The slices are only valid during the call.
+type WriteBatchHandler interface {
+	// Put is called for a Value record.
+	Put(cf uint32, key, value []byte)
+	// Merge is called for a Merge record.
+	Merge(cf uint32, key, value []byte)
+	// PutBlobIndex is called for a BlobIndex record.
+	PutBlobIndex(cf uint32, key, value []byte)
+	// Delete is called for a Deletion record.
+	Delete(cf uint32, key []byte)
+	// SingleDelete is called for a SingleDeletion record.
+	SingleDelete(cf uint32, key []byte)
+	// DeleteRange is called for a RangeDeletion record.
+	DeleteRange(cf uint32, begin, end []byte)
+	// LogData is called for a LogData record.
+	LogData(blob []byte)
+	// MarkBeginPrepare is called for a BeginPrepareXID,
+	// BeginPersistedPrepareXID or BeginUnprepareXID record.
+	MarkBeginPrepare(recordType WriteBatchRecordType)
+	// MarkEndPrepare is called for an EndPrepareXID record.
+	MarkEndPrepare(xid []byte)
+	// MarkCommit is called for a CommitXID record.
+	MarkCommit(xid []byte)
+	// MarkRollback is called for a RollbackXID record.
+	MarkRollback(xid []byte)
+	// MarkNoop is called for a Noop record.
+	MarkNoop()
+}
+
+// Iterate calls back the handler for the records in the batch in order.
+// The records are decoded by WriteBatchIterator rather than
+// rocksdb_writebatch_iterate, which only reports the Value and Deletion
+// records in the default column family and stops at the other records.
+// It returns the error if the batch can not be decoded, the records before
+// the error are still called back.
+func (wb *WriteBatch) Iterate(handler WriteBatchHandler) error {
+	iter := wb.NewIterator()
+	for iter.Next() {
+		r := iter.Record()
+		switch r.Type {
+		case WriteBatchRecordTypeValue:
+			handler.Put(r.ColumnFamilyID, r.Key, r.Value)
+		case WriteBatchRecordTypeMerge:
+			handler.Merge(r.ColumnFamilyID, r.Key, r.Value)
+		case WriteBatchRecordTypeBlobIndex:
+			handler.PutBlobIndex(r.ColumnFamilyID, r.Key, r.Value)
+		case WriteBatchRecordTypeDeletion:
+			handler.Delete(r.ColumnFamilyID, r.Key)
+		case WriteBatchRecordTypeSingleDeletion:
+			handler.SingleDelete(r.ColumnFamilyID, r.Key)
+		case WriteBatchRecordTypeRangeDeletion:
+			handler.DeleteRange(r.ColumnFamilyID, r.Key, r.End)
+		case WriteBatchRecordTypeLogData:
+			handler.LogData(r.Key)
+		case WriteBatchRecordTypeBeginPrepareXID, WriteBatchRecordTypeBeginPersistedPrepareXID,
+			WriteBatchRecordTypeBeginUnprepareXID:
+			handler.MarkBeginPrepare(r.Type)
+		case WriteBatchRecordTypeEndPrepareXID:
+			handler.MarkEndPrepare(r.Key)
+		case WriteBatchRecordTypeCommitXID:
+			handler.MarkCommit(r.Key)
+		case WriteBatchRecordTypeRollbackXID:
+			handler.MarkRollback(r.Key)
+		case WriteBatchRecordTypeNoop:
+			handler.MarkNoop()
+		}
+	}
+	return iter.Error()
+}
+
+// Clear removes all the enqueued Put and Deletes, and the save points.
 func (wb *WriteBatch) Clear() {
 	C.rocksdb_writebatch_clear(wb.c)
+	wb.savePoints = 0
 }
 
 // Destroy deallocates the WriteBatch object.
@@ -114,17 +297,68 @@ type WriteBatchRecordType byte
 
 // Types of batch records.
 const (
-	WriteBatchRecordTypeDeletion WriteBatchRecordType = 0x0
-	WriteBatchRecordTypeValue    WriteBatchRecordType = 0x1
-	WriteBatchRecordTypeMerge    WriteBatchRecordType = 0x2
-	WriteBatchRecordTypeLogData  WriteBatchRecordType = 0x3
+	WriteBatchRecordTypeDeletion                 WriteBatchRecordType = 0x0
+	WriteBatchRecordTypeValue                    WriteBatchRecordType = 0x1
+	WriteBatchRecordTypeMerge                    WriteBatchRecordType = 0x2
+	WriteBatchRecordTypeLogData                  WriteBatchRecordType = 0x3
+	WriteBatchRecordTypeSingleDeletion           WriteBatchRecordType = 0x7
+	WriteBatchRecordTypeBeginPrepareXID          WriteBatchRecordType = 0x9
+	WriteBatchRecordTypeEndPrepareXID            WriteBatchRecordType = 0xA
+	WriteBatchRecordTypeCommitXID                WriteBatchRecordType = 0xB
+	WriteBatchRecordTypeRollbackXID              WriteBatchRecordType = 0xC
+	WriteBatchRecordTypeNoop                     WriteBatchRecordType = 0xD
+	WriteBatchRecordTypeRangeDeletion            WriteBatchRecordType = 0xF
+	WriteBatchRecordTypeBlobIndex                WriteBatchRecordType = 0x11
+	WriteBatchRecordTypeBeginPersistedPrepareXID WriteBatchRecordType = 0x12
+	WriteBatchRecordTypeBeginUnprepareXID        WriteBatchRecordType = 0x13
+
+	// the tags of the records in the non-default column families, which
+	// are reported as the types above with the ColumnFamilyID.
+	writeBatchRecordTypeColumnFamilyDeletion       WriteBatchRecordType = 0x4
+	writeBatchRecordTypeColumnFamilyValue          WriteBatchRecordType = 0x5
+	writeBatchRecordTypeColumnFamilyMerge          WriteBatchRecordType = 0x6
+	writeBatchRecordTypeColumnFamilySingleDeletion WriteBatchRecordType = 0x8
+	writeBatchRecordTypeColumnFamilyRangeDeletion  WriteBatchRecordType = 0xE
+	writeBatchRecordTypeColumnFamilyBlobIndex      WriteBatchRecordType = 0x10
 )
 
+// columnFamilyRecordTypes maps the tags of the records in the non-default
+// column families to the types of the records.
+var columnFamilyRecordTypes = map[WriteBatchRecordType]WriteBatchRecordType{
+	writeBatchRecordTypeColumnFamilyDeletion:       WriteBatchRecordTypeDeletion,
+	writeBatchRecordTypeColumnFamilyValue:          WriteBatchRecordTypeValue,
+	writeBatchRecordTypeColumnFamilyMerge:          WriteBatchRecordTypeMerge,
+	writeBatchRecordTypeColumnFamilySingleDeletion: WriteBatchRecordTypeSingleDeletion,
+	writeBatchRecordTypeColumnFamilyRangeDeletion:  WriteBatchRecordTypeRangeDeletion,
+	writeBatchRecordTypeColumnFamilyBlobIndex:      WriteBatchRecordTypeBlobIndex,
+}
+
+// HasSequence returns true if the record consumes a sequence number when
+// it's written, which are the records counted by WriteBatch.Count. The
+// LogData records and the transaction markers don't.
+func (t WriteBatchRecordType) HasSequence() bool {
+	switch t {
+	case WriteBatchRecordTypeDeletion, WriteBatchRecordTypeValue,
+		WriteBatchRecordTypeMerge, WriteBatchRecordTypeSingleDeletion,
+		WriteBatchRecordTypeRangeDeletion, WriteBatchRecordTypeBlobIndex:
+		return true
+	}
+	return false
+}
+
 // WriteBatchRecord represents a record inside a WriteBatch.
 type WriteBatchRecord struct {
+	// Key is the key of the record, the begin key of a RangeDeletion record,
+	// the blob of a LogData record, or the transaction name of an
+	// EndPrepareXID, CommitXID or RollbackXID record.
 	Key   []byte
 	Value []byte
-	Type  WriteBatchRecordType
+	// End is the end key of a RangeDeletion record.
+	End  []byte
+	Type WriteBatchRecordType
+	// ColumnFamilyID is the id of the column family, 0 for the default
+	// column family.
+	ColumnFamilyID uint32
 }
 
 // WriteBatchIterator represents a iterator to iterator over records.
@@ -143,36 +377,66 @@ func (iter *WriteBatchIterator) Next() bool {
 	// reset the current record
 	iter.record.Key = nil
 	iter.record.Value = nil
+	iter.record.End = nil
+	iter.record.ColumnFamilyID = 0
 
 	// parse the record type
 	recordType := WriteBatchRecordType(iter.data[0])
-	iter.record.Type = recordType
 	iter.data = iter.data[1:]
 
-	// parse the key
-	x, n := iter.decodeVarint(iter.data)
-	if n == 0 {
-		iter.err = io.ErrShortBuffer
-		return false
-	}
-	k := n + int(x)
-	iter.record.Key = iter.data[n:k]
-	iter.data = iter.data[k:]
-
-	// parse the data
-	if recordType == WriteBatchRecordTypeValue || recordType == WriteBatchRecordTypeMerge {
+	// parse the column family id
+	if t, ok := columnFamilyRecordTypes[recordType]; ok {
 		x, n := iter.decodeVarint(iter.data)
 		if n == 0 {
 			iter.err = io.ErrShortBuffer
 			return false
 		}
-		k := n + int(x)
-		iter.record.Value = iter.data[n:k]
-		iter.data = iter.data[k:]
+		iter.record.ColumnFamilyID = uint32(x)
+		iter.data = iter.data[n:]
+		recordType = t
+	}
+	iter.record.Type = recordType
+
+	var ok bool
+	switch recordType {
+	case WriteBatchRecordTypeValue, WriteBatchRecordTypeMerge, WriteBatchRecordTypeBlobIndex:
+		if iter.record.Key, ok = iter.decodeSlice(); ok {
+			iter.record.Value, ok = iter.decodeSlice()
+		}
+	case WriteBatchRecordTypeRangeDeletion:
+		if iter.record.Key, ok = iter.decodeSlice(); ok {
+			iter.record.End, ok = iter.decodeSlice()
+		}
+	case WriteBatchRecordTypeDeletion, WriteBatchRecordTypeSingleDeletion,
+		WriteBatchRecordTypeLogData, WriteBatchRecordTypeEndPrepareXID,
+		WriteBatchRecordTypeCommitXID, WriteBatchRecordTypeRollbackXID:
+		iter.record.Key, ok = iter.decodeSlice()
+	case WriteBatchRecordTypeBeginPrepareXID, WriteBatchRecordTypeBeginPersistedPrepareXID,
+		WriteBatchRecordTypeBeginUnprepareXID, WriteBatchRecordTypeNoop:
+		ok = true
+	default:
+		iter.err = fmt.Errorf("unknown write batch record type: %d", recordType)
+		return false
+	}
+	if !ok {
+		iter.err = io.ErrShortBuffer
+		return false
 	}
 	return true
 }
 
+// decodeSlice decodes a varint length prefixed slice.
+func (iter *WriteBatchIterator) decodeSlice() ([]byte, bool) {
+	x, n := iter.decodeVarint(iter.data)
+	if n == 0 || uint64(len(iter.data)-n) < x {
+		return nil, false
+	}
+	k := n + int(x)
+	v := iter.data[n:k]
+	iter.data = iter.data[k:]
+	return v, true
+}
+
 // Record returns the current record.
 func (iter *WriteBatchIterator) Record() *WriteBatchRecord {
 	return &iter.record
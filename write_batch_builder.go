@@ -0,0 +1,162 @@
+package gorocksdb
+
+// #include <stdlib.h>
+// #include "rocksdb/c.h"
+// #include "gorocksdb.h"
+import "C"
+import (
+	"encoding/binary"
+	"errors"
+	"unsafe"
+)
+
+// WriteBatchBuilder builds a serialized WriteBatch in Go memory, so adding
+// a record does not cross cgo like WriteBatch does. The built batch is
+// written by DB.WriteBuilder in a single cgo call, or converted to a
+// WriteBatch by Build.
+//
+// The representation is the same as rocksdb's WriteBatch:
+//
+//	sequence (8 bytes), count (4 bytes), records
+//
+// where a record is a type tag, an optional varint column family id and
+// the varint length prefixed key and value.
+// A WriteBatchBuilder is not safe for concurrent use.
+type WriteBatchBuilder struct {
+	data  []byte
+	count uint32
+}
+
+// NewWriteBatchBuilder creates a WriteBatchBuilder object with the
+// reserved bytes for the records.
+func NewWriteBatchBuilder(reservedBytes int) *WriteBatchBuilder {
+	return &WriteBatchBuilder{data: make([]byte, kHeader, kHeader+reservedBytes)}
+}
+
+// Put queues a key-value pair.
+func (b *WriteBatchBuilder) Put(key, value []byte) {
+	b.appendRecord(WriteBatchRecordTypeValue, writeBatchRecordTypeColumnFamilyValue, 0, key, value, true)
+}
+
+// PutCF queues a key-value pair in a column family.
+func (b *WriteBatchBuilder) PutCF(cf *ColumnFamilyHandle, key, value []byte) {
+	b.appendRecord(WriteBatchRecordTypeValue, writeBatchRecordTypeColumnFamilyValue, cf.ID(), key, value, true)
+}
+
+// Merge queues a merge of "value" with the existing value of "key".
+func (b *WriteBatchBuilder) Merge(key, value []byte) {
+	b.appendRecord(WriteBatchRecordTypeMerge, writeBatchRecordTypeColumnFamilyMerge, 0, key, value, true)
+}
+
+// MergeCF queues a merge of "value" with the existing value of "key" in a
+// column family.
+func (b *WriteBatchBuilder) MergeCF(cf *ColumnFamilyHandle, key, value []byte) {
+	b.appendRecord(WriteBatchRecordTypeMerge, writeBatchRecordTypeColumnFamilyMerge, cf.ID(), key, value, true)
+}
+
+// Delete queues a deletion of the data at key.
+func (b *WriteBatchBuilder) Delete(key []byte) {
+	b.appendRecord(WriteBatchRecordTypeDeletion, writeBatchRecordTypeColumnFamilyDeletion, 0, key, nil, false)
+}
+
+// DeleteCF queues a deletion of the data at key in a column family.
+func (b *WriteBatchBuilder) DeleteCF(cf *ColumnFamilyHandle, key []byte) {
+	b.appendRecord(WriteBatchRecordTypeDeletion, writeBatchRecordTypeColumnFamilyDeletion, cf.ID(), key, nil, false)
+}
+
+// DeleteRange queues a deletion of the keys in [start, end).
+func (b *WriteBatchBuilder) DeleteRange(start, end []byte) {
+	b.appendRecord(WriteBatchRecordTypeRangeDeletion, writeBatchRecordTypeColumnFamilyRangeDeletion, 0, start, end, true)
+}
+
+// DeleteRangeCF queues a deletion of the keys in [start, end) in a column
+// family.
+func (b *WriteBatchBuilder) DeleteRangeCF(cf *ColumnFamilyHandle, start, end []byte) {
+	b.appendRecord(WriteBatchRecordTypeRangeDeletion, writeBatchRecordTypeColumnFamilyRangeDeletion, cf.ID(), start, end, true)
+}
+
+// PutLogData appends a blob of metadata to the batch, which is written to
+// the WAL but not to the db.
+func (b *WriteBatchBuilder) PutLogData(blob []byte) {
+	b.data = append(b.data, byte(WriteBatchRecordTypeLogData))
+	b.appendSlice(blob)
+}
+
+func (b *WriteBatchBuilder) appendRecord(recordType, cfRecordType WriteBatchRecordType, cfID uint32, key, value []byte, hasValue bool) {
+	if cfID == 0 {
+		b.data = append(b.data, byte(recordType))
+	} else {
+		b.data = append(b.data, byte(cfRecordType))
+		b.appendVarint(uint64(cfID))
+	}
+	b.appendSlice(key)
+	if hasValue {
+		b.appendSlice(value)
+	}
+	b.count++
+	binary.LittleEndian.PutUint32(b.data[8:kHeader], b.count)
+}
+
+func (b *WriteBatchBuilder) appendSlice(s []byte) {
+	b.appendVarint(uint64(len(s)))
+	b.data = append(b.data, s...)
+}
+
+func (b *WriteBatchBuilder) appendVarint(x uint64) {
+	for x >= 0x80 {
+		b.data = append(b.data, byte(x)|0x80)
+		x >>= 7
+	}
+	b.data = append(b.data, byte(x))
+}
+
+// Data returns the serialized batch, which is valid until the next
+// modification of the builder.
+func (b *WriteBatchBuilder) Data() []byte {
+	return b.data
+}
+
+// Count returns the number of updates in the batch.
+func (b *WriteBatchBuilder) Count() int {
+	return int(b.count)
+}
+
+// NewIterator returns a iterator to iterate over the records in the batch.
+func (b *WriteBatchBuilder) NewIterator() *WriteBatchIterator {
+	return &WriteBatchIterator{data: b.data[kHeader:]}
+}
+
+// Build creates a WriteBatch from the serialized batch.
+func (b *WriteBatchBuilder) Build() *WriteBatch {
+	return WriteBatchFrom(b.data)
+}
+
+// Clear removes all the enqueued records, the memory is reused.
+func (b *WriteBatchBuilder) Clear() {
+	b.data = b.data[:kHeader]
+	for i := range b.data {
+		b.data[i] = 0
+	}
+	b.count = 0
+}
+
+// WriteBuilder writes the batch built by a WriteBatchBuilder to the
+// database in a single cgo call.
+func (db *DB) WriteBuilder(opts *WriteOptions, b *WriteBatchBuilder) error {
+	if err := db.checkWritable("WriteBuilder"); err != nil {
+		return err
+	}
+	var cErr *C.char
+	db.RLock()
+	if db.opened == 0 {
+		db.RUnlock()
+		return errDBClosed
+	}
+	C.gorocksdb_write_raw(db.c, opts.c, byteToChar(b.data), C.size_t(len(b.data)), &cErr)
+	db.RUnlock()
+	if cErr != nil {
+		defer C.free(unsafe.Pointer(cErr))
+		return errors.New(C.GoString(cErr))
+	}
+	return nil
+}
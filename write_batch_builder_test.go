@@ -0,0 +1,131 @@
+package gorocksdb
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/facebookgo/ensure"
+)
+
+func TestWriteBatchBuilder(t *testing.T) {
+	db := newTestDB(t, "TestWriteBatchBuilder", nil)
+	defer db.Close()
+
+	cf, err := db.CreateColumnFamily(NewDefaultOptions(), "guide")
+	ensure.Nil(t, err)
+	defer cf.Destroy()
+
+	// the builder encodes the same representation as WriteBatch
+	wb := NewWriteBatch()
+	defer wb.Destroy()
+	wb.Put([]byte("key1"), []byte("val1"))
+	wb.PutCF(cf, []byte("key2"), []byte("val2"))
+	wb.Merge([]byte("key3"), []byte("val3"))
+	wb.DeleteCF(cf, []byte("key4"))
+	wb.DeleteRange([]byte("a"), []byte("b"))
+	wb.PutLogData([]byte("blob"))
+
+	b := NewWriteBatchBuilder(0)
+	b.Put([]byte("key1"), []byte("val1"))
+	b.PutCF(cf, []byte("key2"), []byte("val2"))
+	b.Merge([]byte("key3"), []byte("val3"))
+	b.DeleteCF(cf, []byte("key4"))
+	b.DeleteRange([]byte("a"), []byte("b"))
+	b.PutLogData([]byte("blob"))
+	ensure.DeepEqual(t, b.Count(), wb.Count())
+	ensure.DeepEqual(t, b.Data(), wb.Data())
+
+	built := b.Build()
+	ensure.DeepEqual(t, built.Data(), wb.Data())
+	built.Destroy()
+
+	b.Clear()
+	ensure.DeepEqual(t, b.Count(), 0)
+	b.Put([]byte("key5"), []byte("val5"))
+	b.PutCF(cf, []byte("key6"), []byte("val6"))
+	wo := NewDefaultWriteOptions()
+	ensure.Nil(t, db.WriteBuilder(wo, b))
+
+	ro := NewDefaultReadOptions()
+	v, err := db.GetBytes(ro, []byte("key5"))
+	ensure.Nil(t, err)
+	ensure.DeepEqual(t, v, []byte("val5"))
+	s, err := db.GetCF(ro, cf, []byte("key6"))
+	ensure.Nil(t, err)
+	ensure.DeepEqual(t, s.Data(), []byte("val6"))
+	s.Free()
+}
+
+func benchmarkWriteBatchKeys(n int) ([][]byte, []byte) {
+	keys := make([][]byte, n)
+	for i := range keys {
+		keys[i] = []byte(fmt.Sprintf("key%08d", i))
+	}
+	return keys, make([]byte, 100)
+}
+
+func BenchmarkWriteBatchPut(b *testing.B) {
+	keys, value := benchmarkWriteBatchKeys(1000)
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		wb := NewWriteBatch()
+		for _, key := range keys {
+			wb.Put(key, value)
+		}
+		wb.Destroy()
+	}
+}
+
+func BenchmarkWriteBatchBuilderPut(b *testing.B) {
+	keys, value := benchmarkWriteBatchKeys(1000)
+	builder := NewWriteBatchBuilder(len(keys) * (len(keys[0]) + len(value) + 3))
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		builder.Clear()
+		for _, key := range keys {
+			builder.Put(key, value)
+		}
+	}
+}
+
+func BenchmarkDBWriteBatch(b *testing.B) {
+	db := newTestDB(b, "BenchmarkDBWriteBatch", nil)
+	defer db.Close()
+	wo := NewDefaultWriteOptions()
+	wo.DisableWAL(true)
+	keys, value := benchmarkWriteBatchKeys(1000)
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		wb := NewWriteBatch()
+		for _, key := range keys {
+			wb.Put(key, value)
+		}
+		if err := db.Write(wo, wb); err != nil {
+			b.Fatal(err)
+		}
+		wb.Destroy()
+	}
+}
+
+func BenchmarkDBWriteBatchBuilder(b *testing.B) {
+	db := newTestDB(b, "BenchmarkDBWriteBatchBuilder", nil)
+	defer db.Close()
+	wo := NewDefaultWriteOptions()
+	wo.DisableWAL(true)
+	keys, value := benchmarkWriteBatchKeys(1000)
+	builder := NewWriteBatchBuilder(len(keys) * (len(keys[0]) + len(value) + 3))
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		builder.Clear()
+		for _, key := range keys {
+			builder.Put(key, value)
+		}
+		if err := db.WriteBuilder(wo, builder); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
@@ -1,6 +1,7 @@
 package gorocksdb
 
 import (
+	"fmt"
 	"testing"
 
 	"github.com/facebookgo/ensure"
@@ -73,3 +74,222 @@ func TestWriteBatchIterator(t *testing.T) {
 	// there shouldn't be any left
 	ensure.False(t, iter.Next())
 }
+
+func TestWriteBatchIteratorAllTypes(t *testing.T) {
+	db := newTestDB(t, "TestWriteBatchIteratorAllTypes", nil)
+	defer db.Close()
+
+	cf, err := db.CreateColumnFamily(NewDefaultOptions(), "guide")
+	ensure.Nil(t, err)
+	defer cf.Destroy()
+
+	wb := NewWriteBatch()
+	defer wb.Destroy()
+	wb.PutCF(cf, []byte("key1"), []byte("val1"))
+	wb.DeleteRange([]byte("a"), []byte("z"))
+	wb.DeleteCF(cf, []byte("key2"))
+
+	iter := wb.NewIterator()
+	ensure.True(t, iter.Next())
+	record := iter.Record()
+	ensure.DeepEqual(t, record.Type, WriteBatchRecordTypeValue)
+	ensure.DeepEqual(t, record.ColumnFamilyID, uint32(1))
+	ensure.DeepEqual(t, record.Key, []byte("key1"))
+	ensure.DeepEqual(t, record.Value, []byte("val1"))
+
+	ensure.True(t, iter.Next())
+	record = iter.Record()
+	ensure.DeepEqual(t, record.Type, WriteBatchRecordTypeRangeDeletion)
+	ensure.DeepEqual(t, record.ColumnFamilyID, uint32(0))
+	ensure.DeepEqual(t, record.Key, []byte("a"))
+	ensure.DeepEqual(t, record.End, []byte("z"))
+
+	ensure.True(t, iter.Next())
+	record = iter.Record()
+	ensure.DeepEqual(t, record.Type, WriteBatchRecordTypeDeletion)
+	ensure.DeepEqual(t, record.ColumnFamilyID, uint32(1))
+	ensure.DeepEqual(t, record.Key, []byte("key2"))
+
+	ensure.False(t, iter.Next())
+	ensure.Nil(t, iter.Error())
+}
+
+func TestWriteBatchIteratorMarkers(t *testing.T) {
+	data := make([]byte, kHeader)
+	data = append(data, byte(WriteBatchRecordTypeNoop))
+	data = append(data, byte(WriteBatchRecordTypeBeginPrepareXID))
+	data = append(data, byte(writeBatchRecordTypeColumnFamilySingleDeletion), 2, 3, 'k', 'e', 'y')
+	data = append(data, byte(WriteBatchRecordTypeEndPrepareXID), 3, 'x', 'i', 'd')
+	data = append(data, byte(WriteBatchRecordTypeCommitXID), 3, 'x', 'i', 'd')
+	data = append(data, 0x7f)
+	wb := WriteBatchFrom(data)
+	defer wb.Destroy()
+
+	iter := wb.NewIterator()
+	ensure.True(t, iter.Next())
+	ensure.DeepEqual(t, iter.Record().Type, WriteBatchRecordTypeNoop)
+	ensure.True(t, iter.Next())
+	ensure.DeepEqual(t, iter.Record().Type, WriteBatchRecordTypeBeginPrepareXID)
+	ensure.True(t, iter.Next())
+	ensure.DeepEqual(t, iter.Record().Type, WriteBatchRecordTypeSingleDeletion)
+	ensure.DeepEqual(t, iter.Record().ColumnFamilyID, uint32(2))
+	ensure.DeepEqual(t, iter.Record().Key, []byte("key"))
+	ensure.True(t, iter.Next())
+	ensure.DeepEqual(t, iter.Record().Type, WriteBatchRecordTypeEndPrepareXID)
+	ensure.DeepEqual(t, iter.Record().Key, []byte("xid"))
+	ensure.True(t, iter.Next())
+	ensure.DeepEqual(t, iter.Record().Type, WriteBatchRecordTypeCommitXID)
+	// an unknown type is an error rather than a mis-parsed record
+	ensure.False(t, iter.Next())
+	ensure.NotNil(t, iter.Error())
+}
+
+type testWriteBatchHandler struct {
+	calls []string
+}
+
+func (h *testWriteBatchHandler) add(format string, args ...interface{}) {
+	h.calls = append(h.calls, fmt.Sprintf(format, args...))
+}
+
+func (h *testWriteBatchHandler) Put(cf uint32, key, value []byte) {
+	h.add("put %d %s %s", cf, key, value)
+}
+
+func (h *testWriteBatchHandler) Merge(cf uint32, key, value []byte) {
+	h.add("merge %d %s %s", cf, key, value)
+}
+
+func (h *testWriteBatchHandler) PutBlobIndex(cf uint32, key, value []byte) {
+	h.add("blob %d %s %s", cf, key, value)
+}
+
+func (h *testWriteBatchHandler) Delete(cf uint32, key []byte) {
+	h.add("delete %d %s", cf, key)
+}
+
+func (h *testWriteBatchHandler) SingleDelete(cf uint32, key []byte) {
+	h.add("single delete %d %s", cf, key)
+}
+
+func (h *testWriteBatchHandler) DeleteRange(cf uint32, begin, end []byte) {
+	h.add("delete range %d %s %s", cf, begin, end)
+}
+
+func (h *testWriteBatchHandler) LogData(blob []byte) {
+	h.add("log %s", blob)
+}
+
+func (h *testWriteBatchHandler) MarkBeginPrepare(recordType WriteBatchRecordType) {
+	h.add("begin prepare %d", recordType)
+}
+
+func (h *testWriteBatchHandler) MarkEndPrepare(xid []byte) {
+	h.add("end prepare %s", xid)
+}
+
+func (h *testWriteBatchHandler) MarkCommit(xid []byte) {
+	h.add("commit %s", xid)
+}
+
+func (h *testWriteBatchHandler) MarkRollback(xid []byte) {
+	h.add("rollback %s", xid)
+}
+
+func (h *testWriteBatchHandler) MarkNoop() {
+	h.add("noop")
+}
+
+func TestWriteBatchIterate(t *testing.T) {
+	db := newTestDB(t, "TestWriteBatchIterate", nil)
+	defer db.Close()
+	cf, err := db.CreateColumnFamily(NewDefaultOptions(), "guide")
+	ensure.Nil(t, err)
+	defer cf.Destroy()
+
+	wb := NewWriteBatch()
+	defer wb.Destroy()
+	wb.Put([]byte("key1"), []byte("val1"))
+	wb.PutCF(cf, []byte("key2"), []byte("val2"))
+	wb.Merge([]byte("key3"), []byte("val3"))
+	wb.Delete([]byte("key4"))
+	wb.DeleteRange([]byte("key5"), []byte("key7"))
+	wb.PutLogData([]byte("blob"))
+
+	handler := &testWriteBatchHandler{}
+	ensure.Nil(t, wb.Iterate(handler))
+	ensure.DeepEqual(t, handler.calls, []string{
+		"put 0 key1 val1",
+		"put 1 key2 val2",
+		"merge 0 key3 val3",
+		"delete 0 key4",
+		"delete range 0 key5 key7",
+		"log blob",
+	})
+}
+
+func TestWriteBatchSavePoint(t *testing.T) {
+	wb := NewWriteBatch()
+	defer wb.Destroy()
+	wb.Put([]byte("key1"), []byte("val1"))
+	wb.SetSavePoint()
+	size := wb.GetDataSize()
+	wb.Merge([]byte("key2"), []byte("val2"))
+	wb.Delete([]byte("key3"))
+	ensure.DeepEqual(t, wb.Count(), 3)
+	ensure.True(t, wb.HasMerge())
+	ensure.True(t, wb.HasDelete())
+
+	ensure.Nil(t, wb.RollbackToSavePoint())
+	ensure.DeepEqual(t, wb.Count(), 1)
+	ensure.DeepEqual(t, wb.GetDataSize(), size)
+	ensure.True(t, wb.HasPut())
+	ensure.False(t, wb.HasMerge())
+	ensure.False(t, wb.HasDelete())
+
+	// no save point left
+	ensure.NotNil(t, wb.RollbackToSavePoint())
+	wb.SetSavePoint()
+	ensure.Nil(t, wb.PopSavePoint())
+	ensure.NotNil(t, wb.PopSavePoint())
+}
+
+func TestWriteBatchPutLogDataAndAppend(t *testing.T) {
+	wb := NewWriteBatch()
+	defer wb.Destroy()
+	wb.Put([]byte("key1"), []byte("val1"))
+
+	other := NewWriteBatch()
+	defer other.Destroy()
+	other.PutLogData([]byte("blob"))
+	other.Delete([]byte("key2"))
+	ensure.DeepEqual(t, other.Count(), 1)
+
+	ensure.Nil(t, wb.Append(other))
+	ensure.DeepEqual(t, wb.Count(), 2)
+	ensure.DeepEqual(t, other.Count(), 1)
+
+	iter := wb.NewIterator()
+	ensure.True(t, iter.Next())
+	ensure.DeepEqual(t, iter.Record().Type, WriteBatchRecordTypeValue)
+	ensure.True(t, iter.Next())
+	ensure.DeepEqual(t, iter.Record().Type, WriteBatchRecordTypeLogData)
+	ensure.DeepEqual(t, iter.Record().Key, []byte("blob"))
+	ensure.True(t, iter.Next())
+	ensure.DeepEqual(t, iter.Record().Type, WriteBatchRecordTypeDeletion)
+	ensure.False(t, iter.Next())
+
+	// appending an empty batch keeps the batch
+	empty := NewWriteBatch()
+	defer empty.Destroy()
+	ensure.Nil(t, wb.Append(empty))
+	ensure.DeepEqual(t, wb.Count(), 2)
+
+	// the save points would be dropped by appending
+	wb.SetSavePoint()
+	ensure.DeepEqual(t, wb.Append(other), errWriteBatchSavePoints)
+	ensure.DeepEqual(t, wb.Count(), 2)
+	ensure.Nil(t, wb.PopSavePoint())
+	ensure.Nil(t, wb.Append(other))
+	ensure.DeepEqual(t, wb.Count(), 3)
+}
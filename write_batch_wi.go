@@ -0,0 +1,234 @@
+package gorocksdb
+
+// #include <stdlib.h>
+// #include "rocksdb/c.h"
+import "C"
+import (
+	"errors"
+	"unsafe"
+)
+
+var errWBWIDeleteRangeNotSupported = errors.New("delete range is not supported by write batch with index")
+
+// WriteBatchWithIndex is a WriteBatch with a searchable index, so the
+// pending mutations can be read back by GetFromBatch or by an Iterator
+// before the batch is written to the database.
+type WriteBatchWithIndex struct {
+	c *C.rocksdb_writebatch_wi_t
+}
+
+// NewWriteBatchWithIndex creates a WriteBatchWithIndex object.
+// If overwriteKey is true, the index overwrites the old entry when the same
+// key is written again, so the iterator will only return the latest entry
+// of the key; this is required for Merge to be read by GetFromBatch.
+func NewWriteBatchWithIndex(reservedBytes int, overwriteKey bool) *WriteBatchWithIndex {
+	return NewNativeWriteBatchWithIndex(C.rocksdb_writebatch_wi_create(C.size_t(reservedBytes), boolToChar(overwriteKey)))
+}
+
+// NewNativeWriteBatchWithIndex creates a WriteBatchWithIndex object.
+func NewNativeWriteBatchWithIndex(c *C.rocksdb_writebatch_wi_t) *WriteBatchWithIndex {
+	return &WriteBatchWithIndex{c}
+}
+
+// Put queues a key-value pair.
+func (wb *WriteBatchWithIndex) Put(key, value []byte) {
+	cKey := byteToChar(key)
+	cValue := byteToChar(value)
+	C.rocksdb_writebatch_wi_put(wb.c, cKey, C.size_t(len(key)), cValue, C.size_t(len(value)))
+}
+
+// PutCF queues a key-value pair in a column family.
+func (wb *WriteBatchWithIndex) PutCF(cf *ColumnFamilyHandle, key, value []byte) {
+	cKey := byteToChar(key)
+	cValue := byteToChar(value)
+	C.rocksdb_writebatch_wi_put_cf(wb.c, cf.c, cKey, C.size_t(len(key)), cValue, C.size_t(len(value)))
+}
+
+// Merge queues a merge of "value" with the existing value of "key".
+func (wb *WriteBatchWithIndex) Merge(key, value []byte) {
+	cKey := byteToChar(key)
+	cValue := byteToChar(value)
+	C.rocksdb_writebatch_wi_merge(wb.c, cKey, C.size_t(len(key)), cValue, C.size_t(len(value)))
+}
+
+// MergeCF queues a merge of "value" with the existing value of "key" in a
+// column family.
+func (wb *WriteBatchWithIndex) MergeCF(cf *ColumnFamilyHandle, key, value []byte) {
+	cKey := byteToChar(key)
+	cValue := byteToChar(value)
+	C.rocksdb_writebatch_wi_merge_cf(wb.c, cf.c, cKey, C.size_t(len(key)), cValue, C.size_t(len(value)))
+}
+
+// Delete queues a deletion of the data at key.
+func (wb *WriteBatchWithIndex) Delete(key []byte) {
+	cKey := byteToChar(key)
+	C.rocksdb_writebatch_wi_delete(wb.c, cKey, C.size_t(len(key)))
+}
+
+// DeleteCF queues a deletion of the data at key in a column family.
+func (wb *WriteBatchWithIndex) DeleteCF(cf *ColumnFamilyHandle, key []byte) {
+	cKey := byteToChar(key)
+	C.rocksdb_writebatch_wi_delete_cf(wb.c, cf.c, cKey, C.size_t(len(key)))
+}
+
+// DeleteRange is not supported since the range deletion can not be indexed
+// by rocksdb, an error is always returned and nothing is queued. Use a
+// WriteBatch instead.
+func (wb *WriteBatchWithIndex) DeleteRange(start []byte, end []byte) error {
+	return errWBWIDeleteRangeNotSupported
+}
+
+// DeleteRangeCF is not supported, see DeleteRange.
+func (wb *WriteBatchWithIndex) DeleteRangeCF(cf *ColumnFamilyHandle, start []byte, end []byte) error {
+	return errWBWIDeleteRangeNotSupported
+}
+
+// Data returns the serialized version of this batch.
+func (wb *WriteBatchWithIndex) Data() []byte {
+	var cSize C.size_t
+	cValue := C.rocksdb_writebatch_wi_data(wb.c, &cSize)
+	// we should not free cValue because it is referenced by c struct.
+	return charToByte(cValue, cSize)
+}
+
+// Count returns the number of updates in the batch.
+func (wb *WriteBatchWithIndex) Count() int {
+	return int(C.rocksdb_writebatch_wi_count(wb.c))
+}
+
+// NewIterator returns a iterator to iterate over the records in the batch.
+func (wb *WriteBatchWithIndex) NewIterator() *WriteBatchIterator {
+	data := wb.Data()
+	if len(data) < kHeader {
+		return &WriteBatchIterator{}
+	}
+	return &WriteBatchIterator{data: data[kHeader:]}
+}
+
+// GetFromBatch returns the data associated with the key from the batch only.
+// The returned slice is nil if the key is not found or deleted in the batch.
+func (wb *WriteBatchWithIndex) GetFromBatch(opts *Options, key []byte) (*Slice, error) {
+	var (
+		cErr    *C.char
+		cValLen C.size_t
+		cKey    = byteToChar(key)
+	)
+	cValue := C.rocksdb_writebatch_wi_get_from_batch(wb.c, opts.c, cKey, C.size_t(len(key)), &cValLen, &cErr)
+	if cErr != nil {
+		defer C.free(unsafe.Pointer(cErr))
+		return nil, errors.New(C.GoString(cErr))
+	}
+	return NewSlice(cValue, cValLen), nil
+}
+
+// GetFromBatchCF returns the data associated with the key from the batch
+// only in a column family.
+func (wb *WriteBatchWithIndex) GetFromBatchCF(opts *Options, cf *ColumnFamilyHandle, key []byte) (*Slice, error) {
+	var (
+		cErr    *C.char
+		cValLen C.size_t
+		cKey    = byteToChar(key)
+	)
+	cValue := C.rocksdb_writebatch_wi_get_from_batch_cf(wb.c, opts.c, cf.c, cKey, C.size_t(len(key)), &cValLen, &cErr)
+	if cErr != nil {
+		defer C.free(unsafe.Pointer(cErr))
+		return nil, errors.New(C.GoString(cErr))
+	}
+	return NewSlice(cValue, cValLen), nil
+}
+
+// GetFromBatchAndDB returns the data associated with the key from the batch,
+// and from the database if the key is not found in the batch.
+func (wb *WriteBatchWithIndex) GetFromBatchAndDB(db *DB, opts *ReadOptions, key []byte) (*Slice, error) {
+	var (
+		cErr    *C.char
+		cValLen C.size_t
+		cKey    = byteToChar(key)
+	)
+	db.RLock()
+	if db.opened == 0 {
+		db.RUnlock()
+		return nil, errDBClosed
+	}
+	cValue := C.rocksdb_writebatch_wi_get_from_batch_and_db(wb.c, db.c, opts.c, cKey, C.size_t(len(key)), &cValLen, &cErr)
+	db.RUnlock()
+	if cErr != nil {
+		defer C.free(unsafe.Pointer(cErr))
+		return nil, errors.New(C.GoString(cErr))
+	}
+	return NewSlice(cValue, cValLen), nil
+}
+
+// GetFromBatchAndDBCF is like GetFromBatchAndDB but on a column family.
+func (wb *WriteBatchWithIndex) GetFromBatchAndDBCF(db *DB, opts *ReadOptions, cf *ColumnFamilyHandle, key []byte) (*Slice, error) {
+	var (
+		cErr    *C.char
+		cValLen C.size_t
+		cKey    = byteToChar(key)
+	)
+	db.RLock()
+	if db.opened == 0 {
+		db.RUnlock()
+		return nil, errDBClosed
+	}
+	cValue := C.rocksdb_writebatch_wi_get_from_batch_and_db_cf(wb.c, db.c, opts.c, cf.c, cKey, C.size_t(len(key)), &cValLen, &cErr)
+	db.RUnlock()
+	if cErr != nil {
+		defer C.free(unsafe.Pointer(cErr))
+		return nil, errors.New(C.GoString(cErr))
+	}
+	return NewSlice(cValue, cValLen), nil
+}
+
+// NewIteratorWithBase returns an Iterator which overlays the batch contents
+// on the base iterator, the base iterator is owned by the returned iterator
+// and should not be used or closed any more.
+func (wb *WriteBatchWithIndex) NewIteratorWithBase(base *Iterator) *Iterator {
+	return wb.newIteratorWithBase(base, nil)
+}
+
+// NewIteratorWithBaseCF is like NewIteratorWithBase but overlays the batch
+// contents of the column family. The base iterator should be created on the
+// same column family.
+func (wb *WriteBatchWithIndex) NewIteratorWithBaseCF(base *Iterator, cf *ColumnFamilyHandle) *Iterator {
+	return wb.newIteratorWithBase(base, cf)
+}
+
+// newIteratorWithBase moves the base iterator into the returned iterator,
+// which takes over the tracking of the base by its db. The returned iterator
+// is released too if the base is released by closing the db.
+func (wb *WriteBatchWithIndex) newIteratorWithBase(base *Iterator, cf *ColumnFamilyHandle) *Iterator {
+	create := func() *C.rocksdb_iterator_t {
+		defer func() { base.c = nil }()
+		if cf == nil {
+			return C.rocksdb_writebatch_wi_create_iterator_with_base(wb.c, base.c)
+		}
+		return C.rocksdb_writebatch_wi_create_iterator_with_base_cf(wb.c, base.c, cf.c)
+	}
+	if base.db == nil {
+		return NewNativeIterator(unsafe.Pointer(create()))
+	}
+	iter := &Iterator{db: base.db}
+	db := base.db
+	db.resMu.Lock()
+	defer db.resMu.Unlock()
+	if _, ok := db.resources[base]; !ok {
+		iter.released = 1
+		return iter
+	}
+	delete(db.resources, base)
+	iter.c = create()
+	db.resources[iter] = struct{}{}
+	return iter
+}
+
+// Clear removes all the enqueued Put and Deletes.
+func (wb *WriteBatchWithIndex) Clear() {
+	C.rocksdb_writebatch_wi_clear(wb.c)
+}
+
+// Destroy deallocates the WriteBatchWithIndex object.
+func (wb *WriteBatchWithIndex) Destroy() {
+	C.rocksdb_writebatch_wi_destroy(wb.c)
+	wb.c = nil
+}
@@ -0,0 +1,72 @@
+package gorocksdb
+
+import (
+	"testing"
+
+	"github.com/facebookgo/ensure"
+)
+
+func TestWriteBatchWithIndex(t *testing.T) {
+	db := newTestDB(t, "TestWriteBatchWithIndex", nil)
+	defer db.Close()
+
+	var (
+		givenKey1 = []byte("key1")
+		givenVal1 = []byte("val1")
+		givenKey2 = []byte("key2")
+		givenKey3 = []byte("key3")
+		givenVal3 = []byte("val3")
+	)
+	wo := NewDefaultWriteOptions()
+	ro := NewDefaultReadOptions()
+	ensure.Nil(t, db.Put(wo, givenKey2, []byte("foo")))
+	ensure.Nil(t, db.Put(wo, givenKey3, givenVal3))
+
+	wb := NewWriteBatchWithIndex(0, true)
+	defer wb.Destroy()
+	wb.Put(givenKey1, givenVal1)
+	wb.Delete(givenKey2)
+	ensure.DeepEqual(t, wb.Count(), 2)
+	ensure.NotNil(t, wb.DeleteRange(givenKey1, givenKey3))
+
+	// read from the batch only
+	opts := NewDefaultOptions()
+	v1, err := wb.GetFromBatch(opts, givenKey1)
+	defer v1.Free()
+	ensure.Nil(t, err)
+	ensure.DeepEqual(t, v1.Data(), givenVal1)
+	v3, err := wb.GetFromBatch(opts, givenKey3)
+	ensure.Nil(t, err)
+	ensure.True(t, v3.Data() == nil)
+
+	// read from the batch and db
+	v2, err := wb.GetFromBatchAndDB(db, ro, givenKey2)
+	ensure.Nil(t, err)
+	ensure.True(t, v2.Data() == nil)
+	v3, err = wb.GetFromBatchAndDB(db, ro, givenKey3)
+	defer v3.Free()
+	ensure.Nil(t, err)
+	ensure.DeepEqual(t, v3.Data(), givenVal3)
+
+	// iterate over the batch and db
+	base, err := db.NewIterator(ro)
+	ensure.Nil(t, err)
+	iter := wb.NewIteratorWithBase(base)
+	var actualKeys [][]byte
+	for iter.SeekToFirst(); iter.Valid(); iter.Next() {
+		actualKeys = append(actualKeys, iter.Key().Bytes())
+	}
+	ensure.Nil(t, iter.Err())
+	iter.Close()
+	ensure.DeepEqual(t, actualKeys, [][]byte{givenKey1, givenKey3})
+
+	// perform the batch
+	ensure.Nil(t, db.WriteWithIndex(wo, wb))
+	v4, err := db.Get(ro, givenKey1)
+	defer v4.Free()
+	ensure.Nil(t, err)
+	ensure.DeepEqual(t, v4.Data(), givenVal1)
+	v5, err := db.Get(ro, givenKey2)
+	ensure.Nil(t, err)
+	ensure.True(t, v5.Data() == nil)
+}